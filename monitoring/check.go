@@ -0,0 +1,29 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+
+	"github.com/layerzero-sre/goat-monitor/collector"
+)
+
+// runCheck queries every node once, writes the /health payload to out and
+// returns the process exit code: 0 if the status is "ok" or "warning", 1 if
+// it is "degraded" (matching the 200/503 split of /health). it lets the same
+// binary act as a deploy gate in CI without serving HTTP.
+func runCheck(out io.Writer, c *collector.GoatCollector, t healthThresholds) int {
+	resp := buildHealth(c, t)
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(resp); err != nil {
+		slog.Error("error encoding health response", "error", err)
+		return 1
+	}
+
+	if resp.Status == statusDegraded {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,31 @@
+package collector
+
+import (
+	"runtime"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// NewBuildInfoCollector returns a collector for goat_monitor_build_info, a
+// gauge that is always 1 and carries the running build's version, commit,
+// and Go version as labels. constLabels are added as with WithConstLabels.
+func NewBuildInfoCollector(version, commit string, constLabels prometheus.Labels) prometheus.Collector {
+	labels := prometheus.Labels{
+		"version":    version,
+		"commit":     commit,
+		"go_version": runtime.Version(),
+	}
+	for name, value := range constLabels {
+		labels[name] = value
+	}
+
+	info := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Subsystem:   "monitor",
+		Name:        "build_info",
+		Help:        "build information for the running goat-monitor, always 1",
+		ConstLabels: labels,
+	})
+	info.Set(1)
+	return info
+}
@@ -1,94 +1,316 @@
-// package collector implements a Prometheus collector that queries
-// a goat (EVM-compatible) RPC node for block height, chain ID, and sync status.
+// package collector implements a Prometheus collector that queries one or
+// more goat (EVM-compatible) RPC nodes for block height, chain ID, sync
+// status, peer count, gas and priority fees, and latest block timestamp.
 package collector
 
 import (
-	"log"
+	"log/slog"
+	"math/big"
+	"sync"
+	"time"
 
-	"github.com/layerzero-sre/goat-monitor/rpc"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 const namespace = "goat"
 
-// GoatCollector collects metrics from a goat RPC node.
+// maxConcurrentNodes bounds how many nodes are queried at once per scrape.
+const maxConcurrentNodes = 4
+
+// feeHistoryPercentile is the reward percentile used to derive the priority
+// fee from eth_feeHistory when eth_maxPriorityFeePerGas is unavailable.
+const feeHistoryPercentile = 50
+
+// Node is a single monitored RPC endpoint.
+type Node struct {
+	// Endpoint is the RPC URL, used as the "endpoint" label value
+	Endpoint string
+	Client   NodeClient
+	// Watcher records block height changes across scrapes for stall detection
+	Watcher *BlockWatcher
+	// Heads is fed by a newHeads subscription for WebSocket endpoints; nil
+	// for HTTP endpoints, which are polled
+	Heads *HeadCache
+}
+
+// NewNode creates a Node for the given endpoint and client, with a fresh
+// BlockWatcher. clients that support head subscriptions (see HeadSubscriber)
+// also get a HeadCache, which the caller is expected to feed via
+// SubscribeNewHeads.
+func NewNode(endpoint string, client NodeClient) Node {
+	n := Node{
+		Endpoint: endpoint,
+		Client:   client,
+		Watcher:  NewBlockWatcher(),
+	}
+	if hs, ok := client.(HeadSubscriber); ok && hs.IsWebSocket() {
+		n.Heads = NewHeadCache()
+	}
+	return n
+}
+
+// GoatCollector collects metrics from one or more goat RPC nodes. every
+// metric carries an "endpoint" label identifying the node it came from.
 type GoatCollector struct {
-	client *rpc.Client
+	nodes  []Node
+	logger *slog.Logger
+
+	// addresses whose balances are tracked, see WithWatchAddresses
+	watchAddresses []string
+
+	// static labels added to every metric, see WithConstLabels
+	constLabels prometheus.Labels
+
+	// nil unless WithExpectedChainID is used
+	expectedChainID *uint64
+
+	// snapshot cache, see WithCacheTTL, and the refresh in progress if any,
+	// which concurrent callers wait on instead of each querying the nodes.
+	// mu guards both but is not held while the nodes are queried.
+	cacheTTL time.Duration
+	mu       sync.Mutex
+	cached   *Snapshot
+	inflight *snapshotCall
 
 	// metric descriptors
-	blockHeight *prometheus.Desc
-	chainID     *prometheus.Desc
-	syncing     *prometheus.Desc
-	rpcUp       *prometheus.Desc
+	blockHeight  *prometheus.Desc
+	blockAge     *prometheus.Desc
+	chainID      *prometheus.Desc
+	chainIDMatch *prometheus.Desc
+	syncing      *prometheus.Desc
+	peerCount    *prometheus.Desc
+	gasPrice     *prometheus.Desc
+	priorityFee  *prometheus.Desc
+	txPending    *prometheus.Desc
+	txQueued     *prometheus.Desc
+	blockTime    *prometheus.Desc
+	blockTimeAge *prometheus.Desc
+	rpcUp        *prometheus.Desc
+	snapshotAge  *prometheus.Desc
+	balance      *prometheus.Desc
 }
 
-// NewGoatCollector creates a new collector for the given RPC client.
-func NewGoatCollector(client *rpc.Client) *GoatCollector {
-	return &GoatCollector{
-		client: client,
-		blockHeight: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "", "block_height"),
-			"current block height of the goat node",
-			nil, nil,
-		),
-		chainID: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "", "chain_id"),
-			"chain ID reported by the goat node",
-			nil, nil,
-		),
-		syncing: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "", "syncing"),
-			"whether the goat node is syncing (1=syncing, 0=synced)",
-			nil, nil,
-		),
-		rpcUp: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "", "rpc_up"),
-			"whether the goat RPC endpoint is reachable (1=up, 0=down)",
-			nil, nil,
-		),
+// NewGoatCollector creates a new collector for the given nodes. RPC failures
+// are logged by the clients themselves; the collector logs only what it
+// does with them, tagged with the node's endpoint.
+func NewGoatCollector(nodes []Node, logger *slog.Logger, opts ...Option) *GoatCollector {
+	c := &GoatCollector{
+		nodes:  nodes,
+		logger: logger,
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+
+	// descriptors are built after the options so they pick up any constant
+	// labels (see WithConstLabels)
+	labels := []string{"endpoint"}
+	c.blockHeight = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "block_height"),
+		"current block height of the goat node",
+		labels, c.constLabels,
+	)
+	c.blockAge = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "seconds_since_last_block"),
+		"seconds since the goat node's block height last changed",
+		labels, c.constLabels,
+	)
+	c.chainID = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "chain_id"),
+		"chain ID reported by the goat node",
+		labels, c.constLabels,
+	)
+	c.chainIDMatch = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "chain_id_match"),
+		"whether the node's chain ID matches EXPECTED_CHAIN_ID (1=match, 0=mismatch)",
+		labels, c.constLabels,
+	)
+	c.syncing = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "syncing"),
+		"whether the goat node is syncing (1=syncing, 0=synced)",
+		labels, c.constLabels,
+	)
+	c.peerCount = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "peer_count"),
+		"number of peers connected to the goat node",
+		labels, c.constLabels,
+	)
+	c.gasPrice = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "gas_price_wei"),
+		"current gas price reported by the goat node, in wei",
+		labels, c.constLabels,
+	)
+	c.priorityFee = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "max_priority_fee_wei"),
+		"suggested EIP-1559 priority fee (tip) on the goat node, in wei",
+		labels, c.constLabels,
+	)
+	c.txPending = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "txpool_pending"),
+		"number of pending (executable) transactions in the goat node's mempool",
+		labels, c.constLabels,
+	)
+	c.txQueued = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "txpool_queued"),
+		"number of queued (non-executable) transactions in the goat node's mempool",
+		labels, c.constLabels,
+	)
+	c.blockTime = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "latest_block_timestamp"),
+		"unix timestamp of the latest block on the goat node",
+		labels, c.constLabels,
+	)
+	c.blockTimeAge = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "latest_block_age_seconds"),
+		"seconds between now and the latest block's timestamp",
+		labels, c.constLabels,
+	)
+	c.rpcUp = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "rpc_up"),
+		"whether the goat RPC endpoint is reachable (1=up, 0=down)",
+		labels, c.constLabels,
+	)
+	c.balance = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "account_balance_wei"),
+		"balance of a watched account on the goat node, in wei",
+		[]string{"endpoint", "address"}, c.constLabels,
+	)
+	c.snapshotAge = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "snapshot_age_seconds"),
+		"age of the data served by this scrape (0 unless SCRAPE_CACHE_TTL is set)",
+		nil, c.constLabels,
+	)
+
+	return c
 }
 
 // Describe sends the descriptor for each metric to the provided channel.
 func (c *GoatCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.blockHeight
+	ch <- c.blockAge
 	ch <- c.chainID
+	ch <- c.chainIDMatch
 	ch <- c.syncing
+	ch <- c.peerCount
+	ch <- c.gasPrice
+	ch <- c.priorityFee
+	ch <- c.txPending
+	ch <- c.txQueued
+	ch <- c.blockTime
+	ch <- c.blockTimeAge
 	ch <- c.rpcUp
+	ch <- c.balance
+	ch <- c.snapshotAge
 }
 
-// Collect queries the RPC node and sends metric values to the provided channel.
+// Collect takes a snapshot of every node (possibly cached, see WithCacheTTL)
+// and sends metric values to the provided channel.
 func (c *GoatCollector) Collect(ch chan<- prometheus.Metric) {
-	up := 1.0
-
-	// fetch block height
-	block, err := c.client.GetBlockNumber()
-	if err != nil {
-		log.Printf("error fetching block number: %v", err)
-		up = 0.0
+	snap := c.Snapshot()
+	for _, n := range snap.Nodes {
+		c.collectNode(ch, n)
 	}
-	ch <- prometheus.MustNewConstMetric(c.blockHeight, prometheus.GaugeValue, float64(block))
+	ch <- prometheus.MustNewConstMetric(c.snapshotAge, prometheus.GaugeValue, time.Since(snap.CollectedAt).Seconds())
+}
+
+// collectNode sends the metrics for a single node snapshot to ch.
+func (c *GoatCollector) collectNode(ch chan<- prometheus.Metric, n NodeSnapshot) {
+	ch <- prometheus.MustNewConstMetric(c.blockHeight, prometheus.GaugeValue, float64(n.BlockHeight), n.Endpoint)
 
-	// fetch chain ID
-	chain, err := c.client.GetChainID()
-	if err != nil {
-		log.Printf("error fetching chain id: %v", err)
-		up = 0.0
+	if n.BlockAgeKnown {
+		ch <- prometheus.MustNewConstMetric(c.blockAge, prometheus.GaugeValue, n.BlockAge.Seconds(), n.Endpoint)
 	}
-	ch <- prometheus.MustNewConstMetric(c.chainID, prometheus.GaugeValue, float64(chain))
 
-	// fetch sync status
-	isSyncing, _, err := c.client.GetSyncStatus()
-	if err != nil {
-		log.Printf("error fetching sync status: %v", err)
-		up = 0.0
+	ch <- prometheus.MustNewConstMetric(c.chainID, prometheus.GaugeValue, float64(n.ChainID), n.Endpoint)
+
+	// chain ID check — only when an expected ID is configured
+	if n.ChainIDMatch != nil {
+		match := 0.0
+		if *n.ChainIDMatch {
+			match = 1.0
+		}
+		ch <- prometheus.MustNewConstMetric(c.chainIDMatch, prometheus.GaugeValue, match, n.Endpoint)
 	}
+
 	syncVal := 0.0
-	if isSyncing {
+	if n.Syncing {
 		syncVal = 1.0
 	}
-	ch <- prometheus.MustNewConstMetric(c.syncing, prometheus.GaugeValue, syncVal)
+	ch <- prometheus.MustNewConstMetric(c.syncing, prometheus.GaugeValue, syncVal, n.Endpoint)
+
+	// peer count — some providers disable net_peerCount, so a failure
+	// here skips the metric instead of marking the endpoint as down
+	if n.PeerCountErr == nil {
+		ch <- prometheus.MustNewConstMetric(c.peerCount, prometheus.GaugeValue, float64(n.PeerCount), n.Endpoint)
+	}
+
+	// gas price — optional like peer count, a failure skips the metric
+	if n.GasPriceErr == nil {
+		ch <- prometheus.MustNewConstMetric(c.gasPrice, prometheus.GaugeValue, c.weiToFloat(n.Endpoint, "gas price", n.GasPrice), n.Endpoint)
+	}
+
+	// priority fee — skipped if neither eth_maxPriorityFeePerGas nor the
+	// eth_feeHistory fallback is available
+	if n.PriorityFeeErr == nil {
+		ch <- prometheus.MustNewConstMetric(c.priorityFee, prometheus.GaugeValue, c.weiToFloat(n.Endpoint, "priority fee", n.PriorityFee), n.Endpoint)
+	}
+
+	// txpool — a non-standard namespace many nodes disable, so like peer
+	// count a failure (typically method not found) skips both metrics
+	if n.TxpoolErr == nil {
+		ch <- prometheus.MustNewConstMetric(c.txPending, prometheus.GaugeValue, float64(n.TxpoolPending), n.Endpoint)
+		ch <- prometheus.MustNewConstMetric(c.txQueued, prometheus.GaugeValue, float64(n.TxpoolQueued), n.Endpoint)
+	}
+
+	// latest block timestamp — skipped when the node has no block yet
+	if n.LatestBlockErr == nil {
+		ch <- prometheus.MustNewConstMetric(c.blockTime, prometheus.GaugeValue, float64(n.LatestBlockTimestamp), n.Endpoint)
+		ch <- prometheus.MustNewConstMetric(c.blockTimeAge, prometheus.GaugeValue, n.LatestBlockAge.Seconds(), n.Endpoint)
+	}
+
+	// watched account balances — a failed lookup skips that address only.
+	// any balance above ~0.009 ETH (2^53 wei) is rounded, and the rounding
+	// is far below what a balance alert cares about, so it isn't logged
+	for _, b := range n.Balances {
+		if b.Err == nil {
+			balance, _ := new(big.Float).SetInt(b.Balance).Float64()
+			ch <- prometheus.MustNewConstMetric(c.balance, prometheus.GaugeValue, balance, n.Endpoint, b.Address)
+		}
+	}
 
 	// report RPC availability
-	ch <- prometheus.MustNewConstMetric(c.rpcUp, prometheus.GaugeValue, up)
+	up := 0.0
+	if n.Up() {
+		up = 1.0
+	}
+	ch <- prometheus.MustNewConstMetric(c.rpcUp, prometheus.GaugeValue, up, n.Endpoint)
+}
+
+// forEachNode calls fn with the index and value of every node concurrently,
+// with at most maxConcurrentNodes calls in flight, and returns once all have
+// finished.
+func forEachNode(nodes []Node, fn func(i int, n Node)) {
+	sem := make(chan struct{}, maxConcurrentNodes)
+	var wg sync.WaitGroup
+	for i, n := range nodes {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, n Node) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i, n)
+		}(i, n)
+	}
+	wg.Wait()
+}
+
+// weiToFloat converts a wei amount to float64 for metric emission. values
+// that cannot be represented exactly are still returned (rounded to the
+// nearest float64) but logged, since precision has been lost.
+func (c *GoatCollector) weiToFloat(endpoint, name string, v *big.Int) float64 {
+	f, accuracy := new(big.Float).SetInt(v).Float64()
+	if accuracy != big.Exact {
+		c.logger.Warn("value exceeds float64 precision", "endpoint", endpoint, "metric", name, "wei", v.String(), "emitted", f)
+	}
+	return f
 }
@@ -0,0 +1,92 @@
+package collector
+
+import (
+	"io"
+	"log/slog"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/layerzero-sre/goat-monitor/rpc"
+)
+
+// fakeClient is a NodeClient with canned answers. coreErr fails the core
+// calls; each optional call has its own error field.
+type fakeClient struct {
+	blockNumber uint64
+	chainID     uint64
+	peerCount   uint64
+	coreErr     error
+
+	priorityFee    *big.Int
+	priorityFeeErr error
+	feeReward      *big.Int
+	feeRewardErr   error
+
+	// delay is added to every call, to simulate a slow or hanging node
+	delay time.Duration
+
+	// blockNumberCalls counts GetBlockNumber calls, i.e. snapshots taken
+	blockNumberCalls atomic.Int32
+}
+
+func (f *fakeClient) wait() {
+	time.Sleep(f.delay)
+}
+
+func (f *fakeClient) GetBlockNumber() (uint64, error) {
+	f.blockNumberCalls.Add(1)
+	f.wait()
+	return f.blockNumber, f.coreErr
+}
+
+func (f *fakeClient) GetChainID() (uint64, error) {
+	f.wait()
+	return f.chainID, f.coreErr
+}
+
+func (f *fakeClient) GetSyncStatus() (bool, *rpc.SyncProgress, error) {
+	f.wait()
+	return false, nil, f.coreErr
+}
+
+func (f *fakeClient) GetPeerCount() (uint64, error) {
+	f.wait()
+	return f.peerCount, nil
+}
+
+func (f *fakeClient) GetGasPrice() (*big.Int, error) {
+	f.wait()
+	return big.NewInt(1_000_000_000), nil
+}
+
+func (f *fakeClient) GetMaxPriorityFeePerGas() (*big.Int, error) {
+	f.wait()
+	return f.priorityFee, f.priorityFeeErr
+}
+
+func (f *fakeClient) GetFeeHistoryReward(float64) (*big.Int, error) {
+	f.wait()
+	return f.feeReward, f.feeRewardErr
+}
+
+func (f *fakeClient) GetTxpoolStatus() (uint64, uint64, error) {
+	f.wait()
+	return 0, 0, &rpc.RPCError{Code: rpc.CodeMethodNotFound, Message: "txpool disabled"}
+}
+
+func (f *fakeClient) GetLatestBlockTimestamp() (uint64, error) {
+	f.wait()
+	return uint64(time.Now().Unix()), f.coreErr
+}
+
+func (f *fakeClient) GetBalance(string, string) (*big.Int, error) {
+	f.wait()
+	return big.NewInt(0), nil
+}
+
+// newTestCollector builds a collector over a single fake node.
+func newTestCollector(client NodeClient, opts ...Option) *GoatCollector {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewGoatCollector([]Node{NewNode("http://node:8545", client)}, logger, opts...)
+}
@@ -0,0 +1,51 @@
+package collector
+
+import (
+	"sync"
+
+	"github.com/layerzero-sre/goat-monitor/rpc"
+)
+
+// HeadCache holds the most recent block header pushed by a newHeads
+// subscription. it is written from the subscription goroutine and read
+// during snapshots, so access goes through a mutex.
+type HeadCache struct {
+	mu       sync.RWMutex
+	head     rpc.Head
+	received bool
+}
+
+// NewHeadCache creates an empty cache.
+func NewHeadCache() *HeadCache {
+	return &HeadCache{}
+}
+
+// Update records head as the latest one. heads at or below the cached
+// height are still accepted, since a reorg can legitimately move it back.
+func (h *HeadCache) Update(head rpc.Head) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.head = head
+	h.received = true
+}
+
+// Latest returns the most recent head. ok is false until the first head
+// has been received.
+func (h *HeadCache) Latest() (head rpc.Head, ok bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.head, h.received
+}
+
+// Reset forgets the cached head, so snapshots fall back to polling until
+// the subscription delivers a fresh one. it is called when the
+// subscription's connection drops.
+func (h *HeadCache) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.head = rpc.Head{}
+	h.received = false
+}
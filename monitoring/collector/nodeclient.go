@@ -0,0 +1,47 @@
+package collector
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/layerzero-sre/goat-monitor/rpc"
+)
+
+// NodeClient is the set of RPC queries the collector makes against a node.
+// *rpc.Client implements it; tests can substitute a fake to drive the
+// collector and health logic without a live endpoint.
+type NodeClient interface {
+	GetBlockNumber() (uint64, error)
+	GetChainID() (uint64, error)
+	GetSyncStatus() (bool, *rpc.SyncProgress, error)
+	GetPeerCount() (uint64, error)
+	GetGasPrice() (*big.Int, error)
+	GetMaxPriorityFeePerGas() (*big.Int, error)
+	GetFeeHistoryReward(percentile float64) (*big.Int, error)
+	GetTxpoolStatus() (pending uint64, queued uint64, err error)
+	GetLatestBlockTimestamp() (uint64, error)
+	GetBalance(address string, block string) (*big.Int, error)
+}
+
+// HeadSubscriber is optionally implemented by a NodeClient that can push new
+// block headers instead of being polled for them, as *rpc.Client does for
+// ws:// and wss:// endpoints.
+type HeadSubscriber interface {
+	// IsWebSocket reports whether subscriptions are supported at all
+	IsWebSocket() bool
+	SubscribeNewHeads(ctx context.Context, onHead func(rpc.Head), onDrop func()) error
+}
+
+// CallBounder is optionally implemented by a NodeClient that can report an
+// upper bound on the duration of a call, timeouts and retries included.
+type CallBounder interface {
+	MaxCallDuration(method string) time.Duration
+}
+
+// the concrete client must keep satisfying all three interfaces
+var (
+	_ NodeClient     = (*rpc.Client)(nil)
+	_ HeadSubscriber = (*rpc.Client)(nil)
+	_ CallBounder    = (*rpc.Client)(nil)
+)
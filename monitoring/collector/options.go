@@ -0,0 +1,45 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Option configures a GoatCollector created with NewGoatCollector.
+type Option func(*GoatCollector)
+
+// WithCacheTTL reuses the last successful snapshot for up to ttl instead of
+// re-querying the nodes, so that several scrapers (Prometheus, /health,
+// /metrics-json) don't multiply RPC load. a ttl of 0 disables caching.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(c *GoatCollector) {
+		c.cacheTTL = ttl
+	}
+}
+
+// WithWatchAddresses tracks the wei balance of each address on every node.
+// addresses are expected to be validated by the caller.
+func WithWatchAddresses(addresses []string) Option {
+	return func(c *GoatCollector) {
+		c.watchAddresses = addresses
+	}
+}
+
+// WithConstLabels adds static labels (e.g. env=prod) to every metric the
+// collector emits. label names are expected to be validated by the caller
+// and must not clash with "endpoint" or "address".
+func WithConstLabels(labels prometheus.Labels) Option {
+	return func(c *GoatCollector) {
+		c.constLabels = labels
+	}
+}
+
+// WithExpectedChainID compares each node's reported chain ID against id and
+// exports the result as goat_chain_id_match, catching a monitor that has
+// been pointed at the wrong chain's RPC.
+func WithExpectedChainID(id uint64) Option {
+	return func(c *GoatCollector) {
+		c.expectedChainID = &id
+	}
+}
@@ -0,0 +1,127 @@
+package collector
+
+import (
+	"errors"
+	"time"
+
+	"github.com/layerzero-sre/goat-monitor/rpc"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// rpcDurationBuckets are tuned for JSON-RPC round trips: from 5ms for a
+// local node up to 10s, which matches the client's default timeout.
+var rpcDurationBuckets = []float64{
+	0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10,
+}
+
+// RPCMetrics holds instrumentation for the RPC client itself: call latency,
+// retry counts and failed calls.
+//
+// unlike GoatCollector, which builds const metrics from fresh RPC queries on
+// every scrape, these metrics accumulate state across scrapes: the client
+// updates them on every call (including calls made by GoatCollector.Collect
+// and the /health handler), and a scrape simply reports the current totals.
+// RPCMetrics implements prometheus.Collector; each client gets its own
+// rpc.Observer from ForEndpoint so its calls are labeled by endpoint.
+//
+// the "method" label only ever takes the handful of method names hard-coded
+// in the rpc package's Get* helpers, "endpoint" the configured endpoints
+// and "type" one of three error classes, so cardinality stays bounded.
+type RPCMetrics struct {
+	requestDuration *prometheus.HistogramVec
+	retries         *prometheus.CounterVec
+	errors          *prometheus.CounterVec
+}
+
+// NewRPCMetrics creates the RPC client instrumentation. constLabels are
+// added to every metric, matching WithConstLabels on the GoatCollector.
+func NewRPCMetrics(constLabels prometheus.Labels) *RPCMetrics {
+	return &RPCMetrics{
+		requestDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace:   namespace,
+				Name:        "rpc_request_duration_seconds",
+				Help:        "wall-clock duration of JSON-RPC calls to the goat node",
+				Buckets:     rpcDurationBuckets,
+				ConstLabels: constLabels,
+			},
+			[]string{"endpoint", "method"},
+		),
+		retries: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   namespace,
+				Name:        "rpc_retries_total",
+				Help:        "number of JSON-RPC calls retried after a transient failure",
+				ConstLabels: constLabels,
+			},
+			[]string{"endpoint", "method"},
+		),
+		errors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   namespace,
+				Name:        "rpc_errors_total",
+				Help:        "number of JSON-RPC calls that failed after all attempts, by error type (transport, rpc, invalid_response)",
+				ConstLabels: constLabels,
+			},
+			[]string{"endpoint", "method", "type"},
+		),
+	}
+}
+
+// ForEndpoint returns an rpc.Observer that records calls to endpoint.
+func (m *RPCMetrics) ForEndpoint(endpoint string) rpc.Observer {
+	return endpointObserver{metrics: m, endpoint: endpoint}
+}
+
+// endpointObserver records a single client's calls into the shared
+// RPCMetrics, tagged with the client's endpoint.
+type endpointObserver struct {
+	metrics  *RPCMetrics
+	endpoint string
+}
+
+// ObserveRequest records the duration of a single RPC call.
+func (o endpointObserver) ObserveRequest(method string, duration time.Duration) {
+	o.metrics.requestDuration.WithLabelValues(o.endpoint, method).Observe(duration.Seconds())
+}
+
+// ObserveRetry records that a call is being retried.
+func (o endpointObserver) ObserveRetry(method string) {
+	o.metrics.retries.WithLabelValues(o.endpoint, method).Inc()
+}
+
+// ObserveError records that a call failed, classified by errorType.
+func (o endpointObserver) ObserveError(method string, err error) {
+	o.metrics.errors.WithLabelValues(o.endpoint, method, errorType(err)).Inc()
+}
+
+// errorType classifies a failed call for the "type" label of
+// goat_rpc_errors_total: "transport" when no JSON-RPC answer arrived, "rpc"
+// when the node returned an error object (including method not found on
+// nodes that disable optional methods), and "invalid_response" otherwise.
+func errorType(err error) string {
+	var transportErr *rpc.TransportError
+	var rpcErr *rpc.RPCError
+	switch {
+	case errors.As(err, &transportErr):
+		return "transport"
+	case errors.As(err, &rpcErr):
+		return "rpc"
+	default:
+		return "invalid_response"
+	}
+}
+
+// Describe sends the descriptor for each metric to the provided channel.
+func (m *RPCMetrics) Describe(ch chan<- *prometheus.Desc) {
+	m.requestDuration.Describe(ch)
+	m.retries.Describe(ch)
+	m.errors.Describe(ch)
+}
+
+// Collect sends the current metric values to the provided channel.
+func (m *RPCMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.requestDuration.Collect(ch)
+	m.retries.Collect(ch)
+	m.errors.Collect(ch)
+}
@@ -0,0 +1,30 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/layerzero-sre/goat-monitor/rpc"
+)
+
+func TestErrorType(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"transport", &rpc.TransportError{Err: errors.New("connection refused")}, "transport"},
+		{"wrapped transport", fmt.Errorf("call: %w", &rpc.TransportError{StatusCode: 502, Err: errors.New("bad gateway")}), "transport"},
+		{"method not found", &rpc.RPCError{Code: rpc.CodeMethodNotFound, Message: "not available"}, "rpc"},
+		{"malformed response", errors.New("response ID 7 does not match request ID 3"), "invalid_response"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorType(tt.err); got != tt.want {
+				t.Errorf("errorType(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
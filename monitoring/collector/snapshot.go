@@ -0,0 +1,319 @@
+package collector
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/layerzero-sre/goat-monitor/rpc"
+)
+
+// Snapshot is a point-in-time view of every monitored node. it is the single
+// source of data for the Prometheus collector and the JSON endpoints.
+type Snapshot struct {
+	// CollectedAt is when the last node finished answering, so a fresh
+	// snapshot is zero seconds old however long the queries took
+	CollectedAt time.Time
+	Nodes       []NodeSnapshot
+}
+
+// NodeSnapshot holds the result of querying a single node once. each value
+// is paired with the error from fetching it; the value is meaningless when
+// its error is non-nil.
+type NodeSnapshot struct {
+	Endpoint string
+
+	BlockHeight uint64
+	BlockErr    error
+
+	// BlockAge is the time since the block height last changed; only valid
+	// when BlockAgeKnown is true (i.e. a height has been observed before)
+	BlockAge      time.Duration
+	BlockAgeKnown bool
+
+	ChainID    uint64
+	ChainIDErr error
+
+	// ChainIDMatch reports whether ChainID equals ExpectedChainID; nil when
+	// no expected chain ID is configured or the chain ID couldn't be fetched
+	ChainIDMatch    *bool
+	ExpectedChainID uint64
+
+	Syncing      bool
+	SyncProgress *rpc.SyncProgress
+	SyncErr      error
+
+	PeerCount    uint64
+	PeerCountErr error
+
+	GasPrice    *big.Int
+	GasPriceErr error
+
+	// PriorityFee is the suggested EIP-1559 tip, from eth_maxPriorityFeePerGas
+	// or, where that isn't supported, eth_feeHistory
+	PriorityFee    *big.Int
+	PriorityFeeErr error
+
+	TxpoolPending uint64
+	TxpoolQueued  uint64
+	TxpoolErr     error
+
+	// LatestBlockAge is CollectedAt minus LatestBlockTimestamp
+	LatestBlockTimestamp uint64
+	LatestBlockAge       time.Duration
+	LatestBlockErr       error
+
+	// Balances holds one entry per watched address, in configured order
+	Balances []AccountBalance
+}
+
+// AccountBalance is the balance of a watched address, kept as a big.Int
+// until metric emission since balances routinely exceed uint64.
+type AccountBalance struct {
+	Address string
+	Balance *big.Int
+	Err     error
+}
+
+// Up reports whether the core RPC calls (block number, chain ID, sync
+// status) all succeeded. optional metrics such as peer count don't count,
+// however they failed: a slow or disabled optional method only skips its
+// own metric, matching /health.
+func (s NodeSnapshot) Up() bool {
+	return s.BlockErr == nil && s.ChainIDErr == nil && s.SyncErr == nil
+}
+
+// snapshotCall is a snapshot being taken; done is closed once snap is set.
+type snapshotCall struct {
+	done chan struct{}
+	snap Snapshot
+}
+
+// Snapshot returns the state of every node, in the same order as the
+// collector's nodes. if caching is enabled and the last successful snapshot
+// is younger than the TTL it is returned as-is. otherwise the nodes are
+// queried afresh, unless a refresh is already in progress, in which case
+// its result is shared: while a node is down, concurrent scrapes and
+// health checks then wait out a single round of timeouts rather than one
+// each. callers must treat the result as read-only, since it may be shared.
+func (c *GoatCollector) Snapshot() Snapshot {
+	c.mu.Lock()
+	if c.cacheTTL > 0 && c.cached != nil && time.Since(c.cached.CollectedAt) < c.cacheTTL {
+		snap := *c.cached
+		c.mu.Unlock()
+		return snap
+	}
+	if call := c.inflight; call != nil {
+		c.mu.Unlock()
+		<-call.done
+		return call.snap
+	}
+	call := &snapshotCall{done: make(chan struct{})}
+	c.inflight = call
+	c.mu.Unlock()
+
+	call.snap = c.takeSnapshot()
+
+	c.mu.Lock()
+	c.inflight = nil
+	// only cache fully successful snapshots, so a recovering node is seen
+	// on the very next request rather than after the TTL
+	c.cached = nil
+	if c.cacheTTL > 0 && call.snap.allUp() {
+		c.cached = &call.snap
+	}
+	c.mu.Unlock()
+
+	close(call.done)
+	return call.snap
+}
+
+// allUp reports whether every node in the snapshot is up.
+func (s Snapshot) allUp() bool {
+	for _, n := range s.Nodes {
+		if !n.Up() {
+			return false
+		}
+	}
+	return true
+}
+
+// takeSnapshot queries every node concurrently.
+func (c *GoatCollector) takeSnapshot() Snapshot {
+	snap := Snapshot{
+		Nodes: make([]NodeSnapshot, len(c.nodes)),
+	}
+	forEachNode(c.nodes, func(i int, n Node) {
+		snap.Nodes[i] = c.snapshotNode(n)
+	})
+
+	snap.CollectedAt = time.Now()
+	for i := range snap.Nodes {
+		n := &snap.Nodes[i]
+		if n.LatestBlockErr == nil {
+			n.LatestBlockAge = snap.CollectedAt.Sub(time.Unix(int64(n.LatestBlockTimestamp), 0))
+		}
+	}
+	return snap
+}
+
+// MaxSnapshotDuration returns an upper bound on how long an uncached
+// Snapshot can take when every node times out, or 0 if some node's client
+// does not implement CallBounder. it mirrors the call layout of
+// snapshotNode: the node's calls run concurrently except for the priority
+// fee fallback and the watchlist balances, and at most maxConcurrentNodes
+// nodes are queried at once.
+func (c *GoatCollector) MaxSnapshotDuration() time.Duration {
+	var slowest time.Duration
+	for _, n := range c.nodes {
+		b, ok := n.Client.(CallBounder)
+		if !ok {
+			return 0
+		}
+		branches := []time.Duration{
+			b.MaxCallDuration("eth_blockNumber"),
+			b.MaxCallDuration("eth_getBlockByNumber"),
+			b.MaxCallDuration("eth_chainId"),
+			b.MaxCallDuration("eth_syncing"),
+			b.MaxCallDuration("net_peerCount"),
+			b.MaxCallDuration("eth_gasPrice"),
+			b.MaxCallDuration("eth_maxPriorityFeePerGas") + b.MaxCallDuration("eth_feeHistory"),
+			b.MaxCallDuration("txpool_status"),
+			time.Duration(len(c.watchAddresses)) * b.MaxCallDuration("eth_getBalance"),
+		}
+		for _, d := range branches {
+			slowest = max(slowest, d)
+		}
+	}
+	rounds := (len(c.nodes) + maxConcurrentNodes - 1) / maxConcurrentNodes
+	return time.Duration(rounds) * slowest
+}
+
+// snapshotNode queries a single node. the RPC calls run concurrently so this
+// takes roughly as long as the slowest call; each goroutine writes only its
+// own fields, which are read after the WaitGroup completes. for nodes with a
+// newHeads subscription, block height and timestamp come from the latest
+// pushed head instead of being polled.
+func (c *GoatCollector) snapshotNode(n Node) NodeSnapshot {
+	s := NodeSnapshot{Endpoint: n.Endpoint}
+
+	var wg sync.WaitGroup
+	run := func(f func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f()
+		}()
+	}
+
+	// until the first head arrives, fall back to polling
+	var head rpc.Head
+	haveHead := false
+	if n.Heads != nil {
+		head, haveHead = n.Heads.Latest()
+	}
+	if haveHead {
+		s.BlockHeight = head.Number
+		s.LatestBlockTimestamp = head.Timestamp
+	} else {
+		run(func() {
+			s.BlockHeight, s.BlockErr = n.Client.GetBlockNumber()
+		})
+		run(func() {
+			s.LatestBlockTimestamp, s.LatestBlockErr = n.Client.GetLatestBlockTimestamp()
+		})
+	}
+
+	run(func() {
+		s.ChainID, s.ChainIDErr = n.Client.GetChainID()
+	})
+	run(func() {
+		s.Syncing, s.SyncProgress, s.SyncErr = n.Client.GetSyncStatus()
+	})
+	run(func() {
+		s.PeerCount, s.PeerCountErr = n.Client.GetPeerCount()
+	})
+	run(func() {
+		s.GasPrice, s.GasPriceErr = n.Client.GetGasPrice()
+	})
+	run(func() {
+		s.PriorityFee, s.PriorityFeeErr = priorityFee(n.Client)
+	})
+	run(func() {
+		s.TxpoolPending, s.TxpoolQueued, s.TxpoolErr = n.Client.GetTxpoolStatus()
+	})
+	run(func() {
+		// sequential within this goroutine to bound the load a long
+		// watchlist puts on the node
+		for _, addr := range c.watchAddresses {
+			balance, err := n.Client.GetBalance(addr, "latest")
+			s.Balances = append(s.Balances, AccountBalance{Address: addr, Balance: balance, Err: err})
+		}
+	})
+	wg.Wait()
+
+	if c.expectedChainID != nil && s.ChainIDErr == nil {
+		match := s.ChainID == *c.expectedChainID
+		s.ChainIDMatch = &match
+		s.ExpectedChainID = *c.expectedChainID
+	}
+
+	// time since the height last advanced — on a failed fetch, report the
+	// age of the last known height rather than resetting it
+	if s.BlockErr == nil {
+		s.BlockAge, s.BlockAgeKnown = n.Watcher.Observe(s.BlockHeight), true
+	} else {
+		s.BlockAge, s.BlockAgeKnown = n.Watcher.Age()
+	}
+
+	// failures are already logged at WARN by the client; note which optional
+	// metrics are being skipped as a result
+	logger := c.logger.With("endpoint", n.Endpoint)
+	if s.PeerCountErr != nil {
+		logger.Debug("skipping metric", "metric", "peer_count")
+	}
+	if s.GasPriceErr != nil {
+		logger.Debug("skipping metric", "metric", "gas_price_wei")
+	}
+	if s.PriorityFeeErr != nil {
+		if errors.Is(s.PriorityFeeErr, rpc.ErrMethodNotFound) {
+			logger.Debug("skipping metric: no fee method available", "metric", "max_priority_fee_wei")
+		} else {
+			logger.Debug("skipping metric", "metric", "max_priority_fee_wei")
+		}
+	}
+	if s.TxpoolErr != nil {
+		if errors.Is(s.TxpoolErr, rpc.ErrMethodNotFound) {
+			logger.Debug("skipping metric: txpool namespace not available", "metric", "txpool_pending")
+		} else {
+			logger.Debug("skipping metric", "metric", "txpool_pending")
+		}
+	}
+	if s.LatestBlockErr != nil {
+		if errors.Is(s.LatestBlockErr, rpc.ErrBlockNotFound) {
+			logger.Debug("skipping metric: node has no latest block yet", "metric", "latest_block_timestamp")
+		} else {
+			logger.Debug("skipping metric", "metric", "latest_block_timestamp")
+		}
+	}
+
+	for _, b := range s.Balances {
+		if b.Err != nil {
+			logger.Debug("skipping metric", "metric", "account_balance_wei", "address", b.Address)
+		}
+	}
+
+	return s
+}
+
+// priorityFee returns the suggested priority fee, preferring
+// eth_maxPriorityFeePerGas and falling back to the median reward in the
+// latest block from eth_feeHistory on nodes that don't implement it.
+func priorityFee(client NodeClient) (*big.Int, error) {
+	fee, err := client.GetMaxPriorityFeePerGas()
+	if !errors.Is(err, rpc.ErrMethodNotFound) {
+		return fee, err
+	}
+	return client.GetFeeHistoryReward(feeHistoryPercentile)
+}
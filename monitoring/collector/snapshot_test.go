@@ -0,0 +1,218 @@
+package collector
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/layerzero-sre/goat-monitor/rpc"
+)
+
+func TestSnapshotSharesInflightRefresh(t *testing.T) {
+	// a failing node is never cached, so without sharing every concurrent
+	// caller would wait out its own round of timeouts
+	client := &fakeClient{
+		coreErr: &rpc.TransportError{Err: errors.New("timeout")},
+		delay:   100 * time.Millisecond,
+	}
+	c := newTestCollector(client, WithCacheTTL(time.Minute))
+
+	const callers = 5
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Snapshot()
+		}()
+	}
+	wg.Wait()
+
+	if elapsed := time.Since(start); elapsed > 3*client.delay {
+		t.Errorf("%d concurrent snapshots took %s, want about one refresh (%s)", callers, elapsed, client.delay)
+	}
+	if n := client.blockNumberCalls.Load(); n >= callers {
+		t.Errorf("node queried %d times by %d concurrent callers, want them to share refreshes", n, callers)
+	}
+}
+
+func TestSnapshotCachesOnlySuccess(t *testing.T) {
+	client := &fakeClient{blockNumber: 100, chainID: 2345}
+	c := newTestCollector(client, WithCacheTTL(time.Minute))
+
+	c.Snapshot()
+	c.Snapshot()
+	if n := client.blockNumberCalls.Load(); n != 1 {
+		t.Errorf("healthy node queried %d times within the TTL, want 1", n)
+	}
+
+	client.coreErr = errors.New("down")
+	c.cached = nil
+	c.Snapshot()
+	c.Snapshot()
+	if n := client.blockNumberCalls.Load(); n != 3 {
+		t.Errorf("failing node queried %d times in total, want 3 (failures are not cached)", n)
+	}
+}
+
+func TestSnapshotCollectedAt(t *testing.T) {
+	// a fresh snapshot is stamped when the queries finish, so the age
+	// reported by goat_snapshot_age_seconds stays ~0 without caching
+	client := &fakeClient{blockNumber: 100, chainID: 2345, delay: 100 * time.Millisecond}
+	c := newTestCollector(client)
+
+	snap := c.Snapshot()
+	if age := time.Since(snap.CollectedAt); age > client.delay/2 {
+		t.Errorf("uncached snapshot is %s old, want ~0 despite the %s queries", age, client.delay)
+	}
+	if n := snap.Nodes[0]; n.LatestBlockAge < 0 || n.LatestBlockAge > 2*time.Second {
+		t.Errorf("LatestBlockAge = %s, want ~0 for a block stamped during collection", n.LatestBlockAge)
+	}
+}
+
+func TestNodeSnapshotUp(t *testing.T) {
+	down := errors.New("down")
+	tests := []struct {
+		name string
+		snap NodeSnapshot
+		want bool
+	}{
+		{"all calls succeeded", NodeSnapshot{}, true},
+		{"block number failed", NodeSnapshot{BlockErr: down}, false},
+		{"chain ID failed", NodeSnapshot{ChainIDErr: down}, false},
+		{"sync status failed", NodeSnapshot{SyncErr: down}, false},
+		{"peer count failed", NodeSnapshot{PeerCountErr: down}, true},
+		{"gas price failed", NodeSnapshot{GasPriceErr: down}, true},
+		{"priority fee failed", NodeSnapshot{PriorityFeeErr: down}, true},
+		{"txpool failed", NodeSnapshot{TxpoolErr: down}, true},
+		{"latest block failed", NodeSnapshot{LatestBlockErr: down}, true},
+		{"balance failed", NodeSnapshot{Balances: []AccountBalance{{Address: "0x1", Err: down}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.snap.Up(); got != tt.want {
+				t.Errorf("Up() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnapshotNode(t *testing.T) {
+	tests := []struct {
+		name            string
+		client          *fakeClient
+		expectedChainID uint64
+		wantUp          bool
+		wantMatch       bool
+	}{
+		{
+			name:            "healthy",
+			client:          &fakeClient{blockNumber: 100, chainID: 2345, peerCount: 8, priorityFee: big.NewInt(1)},
+			expectedChainID: 2345,
+			wantUp:          true,
+			wantMatch:       true,
+		},
+		{
+			// the fake's txpool is always disabled and here the priority fee
+			// is too; neither takes the node down
+			name:            "optional methods unsupported",
+			client:          &fakeClient{blockNumber: 100, chainID: 2345, priorityFeeErr: rpc.ErrMethodNotFound, feeRewardErr: rpc.ErrMethodNotFound},
+			expectedChainID: 2345,
+			wantUp:          true,
+			wantMatch:       true,
+		},
+		{
+			name:            "wrong chain",
+			client:          &fakeClient{blockNumber: 100, chainID: 1, priorityFee: big.NewInt(1)},
+			expectedChainID: 2345,
+			wantUp:          true,
+			wantMatch:       false,
+		},
+		{
+			name:            "unreachable",
+			client:          &fakeClient{coreErr: &rpc.TransportError{Err: errors.New("connection refused")}},
+			expectedChainID: 2345,
+			wantUp:          false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCollector(tt.client, WithExpectedChainID(tt.expectedChainID))
+
+			n := c.Snapshot().Nodes[0]
+
+			if n.Up() != tt.wantUp {
+				t.Errorf("Up() = %v, want %v", n.Up(), tt.wantUp)
+			}
+			if !tt.wantUp {
+				if n.ChainIDMatch != nil {
+					t.Errorf("ChainIDMatch = %v, want nil when the chain ID couldn't be fetched", *n.ChainIDMatch)
+				}
+				return
+			}
+			if n.ChainIDMatch == nil || *n.ChainIDMatch != tt.wantMatch {
+				t.Errorf("ChainIDMatch = %v, want %v", n.ChainIDMatch, tt.wantMatch)
+			}
+			if n.TxpoolErr == nil {
+				t.Errorf("TxpoolErr = nil, want the fake's method-not-found error")
+			}
+		})
+	}
+}
+
+func TestPriorityFee(t *testing.T) {
+	errTimeout := errors.New("timeout")
+	notFound := &rpc.RPCError{Code: rpc.CodeMethodNotFound, Message: "the method eth_maxPriorityFeePerGas does not exist"}
+	tests := []struct {
+		name    string
+		client  *fakeClient
+		want    *big.Int
+		wantErr error
+	}{
+		{
+			name:   "eth_maxPriorityFeePerGas supported",
+			client: &fakeClient{priorityFee: big.NewInt(2_000_000_000), feeReward: big.NewInt(1)},
+			want:   big.NewInt(2_000_000_000),
+		},
+		{
+			name:   "falls back to eth_feeHistory",
+			client: &fakeClient{priorityFeeErr: notFound, feeReward: big.NewInt(1_500_000_000)},
+			want:   big.NewInt(1_500_000_000),
+		},
+		{
+			name:    "neither supported",
+			client:  &fakeClient{priorityFeeErr: notFound, feeRewardErr: rpc.ErrMethodNotFound},
+			wantErr: rpc.ErrMethodNotFound,
+		},
+		{
+			// only method-not-found triggers the fallback; a transient
+			// failure is reported as-is
+			name:    "other error does not fall back",
+			client:  &fakeClient{priorityFeeErr: errTimeout, feeReward: big.NewInt(1)},
+			wantErr: errTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := priorityFee(tt.client)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("priorityFee() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("priorityFee() error = %v", err)
+			}
+			if got.Cmp(tt.want) != 0 {
+				t.Errorf("priorityFee() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package collector
+
+import (
+	"sync"
+	"time"
+)
+
+// BlockWatcher tracks when the node's block height last changed, so a node
+// that reports eth_syncing=false but has silently stalled can be detected.
+//
+// the state has to outlive a single scrape, and Collect may run concurrently
+// (overlapping scrapes, /health requests), so it lives here behind a mutex
+// rather than on the collector's stack. a single BlockWatcher is shared by
+// the collector and the /health handler for the same node.
+type BlockWatcher struct {
+	mu         sync.Mutex
+	observed   bool
+	lastHeight uint64
+	lastChange time.Time
+}
+
+// NewBlockWatcher creates a watcher with no observations yet.
+func NewBlockWatcher() *BlockWatcher {
+	return &BlockWatcher{}
+}
+
+// Observe records the latest block height and returns the time elapsed since
+// the height last changed. the first observation counts as a change.
+func (w *BlockWatcher) Observe(height uint64) time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := time.Now()
+	if !w.observed || height != w.lastHeight {
+		w.observed = true
+		w.lastHeight = height
+		w.lastChange = now
+	}
+	return now.Sub(w.lastChange)
+}
+
+// Age returns the time elapsed since the height last changed, without
+// recording a new observation. ok is false if nothing has been observed yet.
+func (w *BlockWatcher) Age() (age time.Duration, ok bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.observed {
+		return 0, false
+	}
+	return time.Since(w.lastChange), true
+}
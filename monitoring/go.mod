@@ -2,7 +2,10 @@ module github.com/layerzero-sre/goat-monitor
 
 go 1.22.0
 
-require github.com/prometheus/client_golang v1.19.0
+require (
+	github.com/gorilla/websocket v1.5.3
+	github.com/prometheus/client_golang v1.19.0
+)
 
 require (
 	github.com/beorn7/perks v1.0.1 // indirect
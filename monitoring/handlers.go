@@ -0,0 +1,371 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/layerzero-sre/goat-monitor/collector"
+)
+
+// node and overall /health statuses, from best to worst. a node's status is
+// the worst condition it trips, so "degraded" takes precedence over
+// "warning" when both apply; the overall status is the worst node status.
+const (
+	statusOK       = "ok"
+	statusWarning  = "warning"
+	statusDegraded = "degraded"
+)
+
+// healthThresholds configures when /health reports a node as degraded or
+// as warning.
+type healthThresholds struct {
+	// degraded if the block height hasn't changed for longer than this
+	MaxBlockAge time.Duration
+	// warning if the block height hasn't changed for longer than this; zero
+	// disables the check
+	WarnBlockAge time.Duration
+	// warning if the node reports fewer peers than this; zero disables the
+	// check, and nodes that don't expose net_peerCount are never warned
+	MinPeerCount uint64
+}
+
+// healthResponse represents the JSON structure returned by /health. the
+// overall status is the worst status of any node.
+type healthResponse struct {
+	Status    string       `json:"status"`
+	Version   string       `json:"version"`
+	Nodes     []nodeHealth `json:"nodes"`
+	Timestamp string       `json:"timestamp"`
+}
+
+// nodeHealth is the health of a single monitored RPC endpoint.
+type nodeHealth struct {
+	Status                string        `json:"status"`
+	NodeEndpoint          string        `json:"node_endpoint"`
+	BlockHeight           uint64        `json:"block_height"`
+	ChainID               uint64        `json:"chain_id"`
+	Syncing               bool          `json:"syncing"`
+	SyncProgress          *syncProgress `json:"sync_progress,omitempty"`
+	PeerCount             *uint64       `json:"peer_count,omitempty"`
+	TxpoolPending         *uint64       `json:"txpool_pending,omitempty"`
+	TxpoolQueued          *uint64       `json:"txpool_queued,omitempty"`
+	SecondsSinceLastBlock float64       `json:"seconds_since_last_block"`
+	Error                 string        `json:"error,omitempty"`
+	Warning               string        `json:"warning,omitempty"`
+}
+
+// syncProgress provides sync details when the node is syncing.
+type syncProgress struct {
+	StartingBlock uint64 `json:"starting_block"`
+	CurrentBlock  uint64 `json:"current_block"`
+	HighestBlock  uint64 `json:"highest_block"`
+}
+
+// readyResponse represents the JSON structure returned by /ready.
+type readyResponse struct {
+	Ready     bool   `json:"ready"`
+	Reason    string `json:"reason,omitempty"`
+	Timestamp string `json:"timestamp"`
+}
+
+// metricsJSONResponse represents the JSON structure returned by
+// /metrics-json: the same values as /metrics, one flat object per node.
+type metricsJSONResponse struct {
+	CollectedAt string            `json:"collected_at"`
+	Nodes       []nodeMetricsJSON `json:"nodes"`
+}
+
+// nodeMetricsJSON holds the metrics for a single node. optional metrics are
+// omitted when the node couldn't provide them, mirroring /metrics.
+type nodeMetricsJSON struct {
+	Endpoint              string   `json:"endpoint"`
+	BlockHeight           uint64   `json:"block_height"`
+	SecondsSinceLastBlock *float64 `json:"seconds_since_last_block,omitempty"`
+	ChainID               uint64   `json:"chain_id"`
+	ChainIDMatch          *int     `json:"chain_id_match,omitempty"`
+	Syncing               int      `json:"syncing"`
+	PeerCount             *uint64  `json:"peer_count,omitempty"`
+	GasPriceWei           *big.Int `json:"gas_price_wei,omitempty"`
+	MaxPriorityFeeWei     *big.Int `json:"max_priority_fee_wei,omitempty"`
+	TxpoolPending         *uint64  `json:"txpool_pending,omitempty"`
+	TxpoolQueued          *uint64  `json:"txpool_queued,omitempty"`
+	LatestBlockTimestamp  *uint64  `json:"latest_block_timestamp,omitempty"`
+	LatestBlockAgeSeconds *float64 `json:"latest_block_age_seconds,omitempty"`
+	// balances of watched addresses that could be fetched, keyed by address
+	AccountBalancesWei map[string]*big.Int `json:"account_balances_wei,omitempty"`
+	RPCUp              int                 `json:"rpc_up"`
+}
+
+// healthHandler snapshots every node and returns a JSON health response
+// with one entry per node. it answers 200 for "ok" and "warning", and 503
+// only for "degraded".
+func healthHandler(w http.ResponseWriter, _ *http.Request, c *collector.GoatCollector, t healthThresholds) {
+	resp := buildHealth(c, t)
+
+	status := http.StatusOK
+	if resp.Status == statusDegraded {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, resp)
+}
+
+// buildHealth snapshots every node and assembles the /health payload. it is
+// shared by the HTTP handler and one-shot check mode.
+func buildHealth(c *collector.GoatCollector, t healthThresholds) healthResponse {
+	snap := c.Snapshot()
+	resp := healthResponse{
+		Status:    statusOK,
+		Version:   version,
+		Nodes:     make([]nodeHealth, len(snap.Nodes)),
+		Timestamp: snap.CollectedAt.UTC().Format(time.RFC3339),
+	}
+
+	for i, n := range snap.Nodes {
+		resp.Nodes[i] = nodeHealthFromSnapshot(n, t)
+		resp.Status = worseStatus(resp.Status, resp.Nodes[i].Status)
+	}
+	return resp
+}
+
+// worseStatus returns whichever of a and b is worse.
+func worseStatus(a, b string) string {
+	rank := map[string]int{statusOK: 0, statusWarning: 1, statusDegraded: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+// nodeHealthFromSnapshot builds the health of a single node. the node is
+// reported as degraded if any core RPC call failed, its chain ID is wrong or
+// its block height has not changed within MaxBlockAge. otherwise it is
+// reported as warning if the height has not changed within WarnBlockAge or
+// it has fewer than MinPeerCount peers.
+func nodeHealthFromSnapshot(n collector.NodeSnapshot, t healthThresholds) nodeHealth {
+	resp := nodeHealth{
+		Status:       statusOK,
+		NodeEndpoint: n.Endpoint,
+		BlockHeight:  n.BlockHeight,
+		ChainID:      n.ChainID,
+		Syncing:      n.Syncing,
+	}
+
+	var errs, warnings []string
+	if n.BlockErr != nil {
+		errs = append(errs, fmt.Sprintf("block number: %v", n.BlockErr))
+	} else {
+		// detect a stalled node: reachable, but the height isn't advancing
+		resp.SecondsSinceLastBlock = n.BlockAge.Seconds()
+		switch {
+		case n.BlockAge > t.MaxBlockAge:
+			errs = append(errs, fmt.Sprintf("no new block for %s (threshold %s)", n.BlockAge.Truncate(time.Second), t.MaxBlockAge))
+		case t.WarnBlockAge > 0 && n.BlockAge > t.WarnBlockAge:
+			warnings = append(warnings, fmt.Sprintf("no new block for %s (warning threshold %s)", n.BlockAge.Truncate(time.Second), t.WarnBlockAge))
+		}
+	}
+	if n.ChainIDErr != nil {
+		errs = append(errs, fmt.Sprintf("chain id: %v", n.ChainIDErr))
+	} else if n.ChainIDMatch != nil && !*n.ChainIDMatch {
+		errs = append(errs, fmt.Sprintf("chain id mismatch: expected %d, node reports %d", n.ExpectedChainID, n.ChainID))
+	}
+	if n.SyncErr != nil {
+		errs = append(errs, fmt.Sprintf("sync status: %v", n.SyncErr))
+	}
+	if n.PeerCountErr == nil && n.PeerCount < t.MinPeerCount {
+		warnings = append(warnings, fmt.Sprintf("only %d peers (minimum %d)", n.PeerCount, t.MinPeerCount))
+	}
+
+	if len(warnings) > 0 {
+		resp.Status = statusWarning
+		resp.Warning = strings.Join(warnings, "; ")
+	}
+	if len(errs) > 0 {
+		resp.Status = statusDegraded
+		resp.Error = strings.Join(errs, "; ")
+	}
+
+	if n.SyncProgress != nil {
+		resp.SyncProgress = &syncProgress{
+			StartingBlock: n.SyncProgress.StartingBlock,
+			CurrentBlock:  n.SyncProgress.CurrentBlock,
+			HighestBlock:  n.SyncProgress.HighestBlock,
+		}
+	}
+
+	// peer count is optional, since some providers disable net_peerCount
+	if n.PeerCountErr == nil {
+		peers := n.PeerCount
+		resp.PeerCount = &peers
+	}
+
+	// likewise txpool, a non-standard namespace many nodes disable
+	if n.TxpoolErr == nil {
+		pending, queued := n.TxpoolPending, n.TxpoolQueued
+		resp.TxpoolPending = &pending
+		resp.TxpoolQueued = &queued
+	}
+
+	return resp
+}
+
+// readyHandler reports whether every node can serve correct data: each must
+// be reachable and not syncing. unlike /health, a syncing node is not ready.
+func readyHandler(w http.ResponseWriter, _ *http.Request, c *collector.GoatCollector) {
+	snap := c.Snapshot()
+	resp := readyResponse{
+		Ready:     true,
+		Timestamp: snap.CollectedAt.UTC().Format(time.RFC3339),
+	}
+
+	var reasons []string
+	for _, n := range snap.Nodes {
+		if reason := notReadyReason(n); reason != "" {
+			reasons = append(reasons, fmt.Sprintf("%s: %s", n.Endpoint, reason))
+		}
+	}
+	if len(reasons) > 0 {
+		resp.Ready = false
+		resp.Reason = strings.Join(reasons, "; ")
+	}
+
+	status := http.StatusOK
+	if !resp.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, resp)
+}
+
+// notReadyReason returns why the node is not ready, or "" if it is.
+func notReadyReason(n collector.NodeSnapshot) string {
+	if n.BlockErr != nil {
+		return fmt.Sprintf("node unreachable: %v", n.BlockErr)
+	}
+	if n.SyncErr != nil {
+		return fmt.Sprintf("sync status: %v", n.SyncErr)
+	}
+	if n.Syncing {
+		if n.SyncProgress != nil {
+			return fmt.Sprintf("node is syncing (block %d of %d)", n.SyncProgress.CurrentBlock, n.SyncProgress.HighestBlock)
+		}
+		return "node is syncing"
+	}
+	return ""
+}
+
+// metricsJSONHandler returns the collector's metrics as flat JSON for
+// consumers that can't parse the Prometheus text format.
+func metricsJSONHandler(w http.ResponseWriter, _ *http.Request, c *collector.GoatCollector) {
+	snap := c.Snapshot()
+	resp := metricsJSONResponse{
+		CollectedAt: snap.CollectedAt.UTC().Format(time.RFC3339),
+		Nodes:       make([]nodeMetricsJSON, len(snap.Nodes)),
+	}
+
+	for i, n := range snap.Nodes {
+		m := nodeMetricsJSON{
+			Endpoint:    n.Endpoint,
+			BlockHeight: n.BlockHeight,
+			ChainID:     n.ChainID,
+			Syncing:     boolToInt(n.Syncing),
+			RPCUp:       boolToInt(n.Up()),
+		}
+		if n.ChainIDMatch != nil {
+			match := boolToInt(*n.ChainIDMatch)
+			m.ChainIDMatch = &match
+		}
+		if n.BlockAgeKnown {
+			age := n.BlockAge.Seconds()
+			m.SecondsSinceLastBlock = &age
+		}
+		if n.PeerCountErr == nil {
+			peers := n.PeerCount
+			m.PeerCount = &peers
+		}
+		if n.GasPriceErr == nil {
+			m.GasPriceWei = n.GasPrice
+		}
+		if n.PriorityFeeErr == nil {
+			m.MaxPriorityFeeWei = n.PriorityFee
+		}
+		if n.TxpoolErr == nil {
+			pending, queued := n.TxpoolPending, n.TxpoolQueued
+			m.TxpoolPending = &pending
+			m.TxpoolQueued = &queued
+		}
+		if n.LatestBlockErr == nil {
+			ts, age := n.LatestBlockTimestamp, n.LatestBlockAge.Seconds()
+			m.LatestBlockTimestamp = &ts
+			m.LatestBlockAgeSeconds = &age
+		}
+		for _, b := range n.Balances {
+			if b.Err != nil {
+				continue
+			}
+			if m.AccountBalancesWei == nil {
+				m.AccountBalancesWei = make(map[string]*big.Int)
+			}
+			m.AccountBalancesWei[b.Address] = b.Balance
+		}
+		resp.Nodes[i] = m
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// livezHandler reports that the process is up and serving HTTP.
+func livezHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, "ok\n")
+}
+
+// basicAuth wraps next so that every request must carry the given HTTP Basic
+// credentials. both values are hashed before comparison so the constant-time
+// compare doesn't leak their lengths.
+func basicAuth(next http.Handler, user, pass string) http.Handler {
+	wantUser := sha256.Sum256([]byte(user))
+	wantPass := sha256.Sum256([]byte(pass))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUser, gotPass, ok := r.BasicAuth()
+		userHash := sha256.Sum256([]byte(gotUser))
+		passHash := sha256.Sum256([]byte(gotPass))
+
+		userOK := subtle.ConstantTimeCompare(userHash[:], wantUser[:]) == 1
+		passOK := subtle.ConstantTimeCompare(passHash[:], wantPass[:]) == 1
+		if !ok || !userOK || !passOK {
+			w.Header().Set("WWW-Authenticate", `Basic realm="goat-monitor", charset="UTF-8"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeJSON writes v as indented JSON with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		slog.Error("error encoding JSON response", "error", err)
+	}
+}
+
+// boolToInt converts a bool to 1 or 0, matching the Prometheus gauges.
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/layerzero-sre/goat-monitor/collector"
+)
+
+func TestNodeHealthFromSnapshot(t *testing.T) {
+	thresholds := healthThresholds{
+		MaxBlockAge:  60 * time.Second,
+		WarnBlockAge: 30 * time.Second,
+		MinPeerCount: 1,
+	}
+	healthy := collector.NodeSnapshot{
+		Endpoint:    "http://node:8545",
+		BlockHeight: 100,
+		ChainID:     2345,
+		PeerCount:   8,
+		BlockAge:    5 * time.Second,
+	}
+	with := func(f func(*collector.NodeSnapshot)) collector.NodeSnapshot {
+		n := healthy
+		f(&n)
+		return n
+	}
+	mismatch := false
+	down := errors.New("down")
+
+	tests := []struct {
+		name        string
+		snap        collector.NodeSnapshot
+		wantStatus  string
+		wantError   string
+		wantWarning string
+	}{
+		{
+			name:       "healthy",
+			snap:       healthy,
+			wantStatus: statusOK,
+		},
+		{
+			name:        "block age past the warning threshold",
+			snap:        with(func(n *collector.NodeSnapshot) { n.BlockAge = 45 * time.Second }),
+			wantStatus:  statusWarning,
+			wantWarning: "no new block for 45s",
+		},
+		{
+			name:       "block age past the degraded threshold",
+			snap:       with(func(n *collector.NodeSnapshot) { n.BlockAge = 90 * time.Second }),
+			wantStatus: statusDegraded,
+			wantError:  "no new block for 1m30s",
+		},
+		{
+			name:        "too few peers",
+			snap:        with(func(n *collector.NodeSnapshot) { n.PeerCount = 0 }),
+			wantStatus:  statusWarning,
+			wantWarning: "only 0 peers",
+		},
+		{
+			name:       "peer count unsupported",
+			snap:       with(func(n *collector.NodeSnapshot) { n.PeerCount, n.PeerCountErr = 0, down }),
+			wantStatus: statusOK,
+		},
+		{
+			name:       "txpool unsupported",
+			snap:       with(func(n *collector.NodeSnapshot) { n.TxpoolErr = down }),
+			wantStatus: statusOK,
+		},
+		{
+			name:       "block number failed",
+			snap:       with(func(n *collector.NodeSnapshot) { n.BlockErr = down }),
+			wantStatus: statusDegraded,
+			wantError:  "block number: down",
+		},
+		{
+			name:       "sync status failed",
+			snap:       with(func(n *collector.NodeSnapshot) { n.SyncErr = down }),
+			wantStatus: statusDegraded,
+			wantError:  "sync status: down",
+		},
+		{
+			name: "chain ID mismatch",
+			snap: with(func(n *collector.NodeSnapshot) {
+				n.ChainID, n.ExpectedChainID, n.ChainIDMatch = 1, 2345, &mismatch
+			}),
+			wantStatus: statusDegraded,
+			wantError:  "chain id mismatch: expected 2345, node reports 1",
+		},
+		{
+			// degraded wins, but the warning is still reported
+			name: "degraded and warning",
+			snap: with(func(n *collector.NodeSnapshot) {
+				n.ChainIDErr = down
+				n.PeerCount = 0
+			}),
+			wantStatus:  statusDegraded,
+			wantError:   "chain id: down",
+			wantWarning: "only 0 peers",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nodeHealthFromSnapshot(tt.snap, thresholds)
+
+			if got.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", got.Status, tt.wantStatus)
+			}
+			if !containsOrEmpty(got.Error, tt.wantError) {
+				t.Errorf("Error = %q, want it to contain %q", got.Error, tt.wantError)
+			}
+			if !containsOrEmpty(got.Warning, tt.wantWarning) {
+				t.Errorf("Warning = %q, want it to contain %q", got.Warning, tt.wantWarning)
+			}
+		})
+	}
+}
+
+// containsOrEmpty reports whether got contains want, or, when want is
+// empty, whether got is empty too.
+func containsOrEmpty(got, want string) bool {
+	if want == "" {
+		return got == ""
+	}
+	return strings.Contains(got, want)
+}
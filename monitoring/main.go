@@ -5,20 +5,46 @@
 //   - current block height (eth_blockNumber)
 //   - chain ID (eth_chainId)
 //   - syncing status (eth_syncing)
+//   - peer count (net_peerCount)
+//   - gas price (eth_gasPrice)
+//   - priority fee (eth_maxPriorityFeePerGas, or eth_feeHistory where that
+//     isn't supported)
+//   - txpool pending/queued counts (txpool_status, where enabled)
+//   - latest block timestamp and age (eth_getBlockByNumber)
+//   - balances of watched addresses (eth_getBalance)
+//   - whether the chain ID matches EXPECTED_CHAIN_ID, if set
+//
+// GOAT_RPC_NODE may list several comma-separated endpoints; each one is
+// queried concurrently and labeled with "endpoint" in every metric.
+// ws:// and wss:// endpoints additionally subscribe to newHeads, so block
+// height and timestamp are pushed rather than polled.
 //
 // endpoints:
 //
-//	GET /metrics — Prometheus scrape endpoint
-//	GET /health  — JSON health dashboard
-//	GET /        — redirects to /health
+//	GET /metrics      — Prometheus scrape endpoint
+//	GET /metrics-json — the same metrics as flat JSON
+//	GET /health       — JSON health dashboard
+//	GET /ready        — readiness: 200 only when reachable and fully synced
+//	GET /livez        — liveness: 200 whenever the process is serving
+//	GET /             — redirects to /health
+//
+// run with -check (or ONESHOT=true) to query the nodes once, print the
+// /health JSON to stdout and exit 0 if ok or warning, 1 if degraded,
+// without serving HTTP — e.g. as a CI deploy gate.
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/layerzero-sre/goat-monitor/collector"
@@ -30,32 +56,34 @@ import (
 const (
 	// default port for the HTTP server
 	defaultPort = "9090"
-)
 
-// healthResponse represents the JSON structure returned by /health.
-type healthResponse struct {
-	Status       string        `json:"status"`
-	NodeEndpoint string        `json:"node_endpoint"`
-	BlockHeight  uint64        `json:"block_height"`
-	ChainID      uint64        `json:"chain_id"`
-	Syncing      bool          `json:"syncing"`
-	SyncProgress *syncProgress `json:"sync_progress,omitempty"`
-	Timestamp    string        `json:"timestamp"`
-	Error        string        `json:"error,omitempty"`
-}
+	// how long to wait for in-flight requests to finish on shutdown
+	shutdownGracePeriod = 10 * time.Second
+)
 
-// syncProgress provides sync details when the node is syncing.
-type syncProgress struct {
-	StartingBlock uint64 `json:"starting_block"`
-	CurrentBlock  uint64 `json:"current_block"`
-	HighestBlock  uint64 `json:"highest_block"`
-}
+// build metadata, set at build time with
+// -ldflags "-X main.version=... -X main.commit=..."
+var (
+	version = "dev"
+	commit  = "unknown"
+)
 
 func main() {
+	checkFlag := flag.Bool("check", false, "query the nodes once, print the health JSON and exit (0=ok, 1=degraded)")
+	flag.Parse()
+
+	// configure logging first so every later failure is logged consistently
+	logger, err := newLogger(os.Getenv("LOG_LEVEL"), os.Getenv("LOG_FORMAT"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid logging configuration: %v\n", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
 	// read required environment variable
-	rpcEndpoint := os.Getenv("GOAT_RPC_NODE")
-	if rpcEndpoint == "" {
-		log.Fatal("GOAT_RPC_NODE environment variable is required")
+	rpcEndpoints, err := parseEndpoints(os.Getenv("GOAT_RPC_NODE"))
+	if err != nil {
+		fatal(logger, "invalid configuration", err)
 	}
 
 	port := os.Getenv("PORT")
@@ -63,15 +91,195 @@ func main() {
 		port = defaultPort
 	}
 
-	log.Printf("starting goat-monitor on :%s", port)
-	log.Printf("monitoring RPC endpoint: %s", rpcEndpoint)
+	oneShot, err := envBool("ONESHOT", false)
+	if err != nil {
+		fatal(logger, "invalid configuration", err)
+	}
+	oneShot = oneShot || *checkFlag
 
-	// initialize RPC client
-	client := rpc.NewClient(rpcEndpoint)
+	selfTest, err := envBool("SELF_TEST", false)
+	if err != nil {
+		fatal(logger, "invalid configuration", err)
+	}
+	selfTestStrict, err := envBool("SELF_TEST_STRICT", false)
+	if err != nil {
+		fatal(logger, "invalid configuration", err)
+	}
+
+	maxAttempts, err := envInt("RPC_MAX_ATTEMPTS", 1)
+	if err != nil {
+		fatal(logger, "invalid configuration", err)
+	}
+	retryBaseDelay, err := envDuration("RPC_RETRY_BASE_DELAY", 250*time.Millisecond)
+	if err != nil {
+		fatal(logger, "invalid configuration", err)
+	}
+
+	// every call runs under a deadline, which also bounds the HTTP write
+	// timeout below, so "no timeout" is not an option
+	rpcTimeout, err := envDuration("RPC_TIMEOUT", 10*time.Second)
+	if err != nil {
+		fatal(logger, "invalid configuration", err)
+	}
+	if rpcTimeout <= 0 {
+		fatal(logger, "invalid configuration", fmt.Errorf("RPC_TIMEOUT must be positive, got %s", rpcTimeout))
+	}
+	rpcMethodTimeouts, err := parseMethodTimeouts(os.Getenv("RPC_METHOD_TIMEOUTS"))
+	if err != nil {
+		fatal(logger, "invalid configuration", fmt.Errorf("RPC_METHOD_TIMEOUTS: %w", err))
+	}
+	rpcHeaders, err := parseKeyValues(os.Getenv("RPC_HEADERS"))
+	if err != nil {
+		fatal(logger, "invalid configuration", fmt.Errorf("RPC_HEADERS: %w", err))
+	}
+
+	maxBlockAgeSeconds, err := envInt("MAX_BLOCK_AGE_SECONDS", 60)
+	if err != nil {
+		fatal(logger, "invalid configuration", err)
+	}
+	if maxBlockAgeSeconds <= 0 {
+		fatal(logger, "invalid configuration", fmt.Errorf("MAX_BLOCK_AGE_SECONDS must be positive, got %d", maxBlockAgeSeconds))
+	}
+	// the warning threshold defaults to half the degraded one, so setting
+	// only MAX_BLOCK_AGE_SECONDS keeps working. an explicit pair that is
+	// inverted is a configuration mistake; an explicit WARN above the
+	// default MAX just never fires.
+	warnBlockAgeSeconds, err := envInt("WARN_BLOCK_AGE_SECONDS", maxBlockAgeSeconds/2)
+	if err != nil {
+		fatal(logger, "invalid configuration", err)
+	}
+	if warnBlockAgeSeconds < 0 {
+		fatal(logger, "invalid configuration", fmt.Errorf("WARN_BLOCK_AGE_SECONDS must not be negative, got %d (0 disables the warning)", warnBlockAgeSeconds))
+	}
+	if warnBlockAgeSeconds > maxBlockAgeSeconds {
+		err := fmt.Errorf("WARN_BLOCK_AGE_SECONDS (%d) exceeds MAX_BLOCK_AGE_SECONDS (%d)", warnBlockAgeSeconds, maxBlockAgeSeconds)
+		if os.Getenv("MAX_BLOCK_AGE_SECONDS") != "" {
+			fatal(logger, "invalid configuration", err)
+		}
+		logger.Warn("block age warning threshold will never fire", "error", err)
+	}
+	minPeerCount, err := envInt("MIN_PEER_COUNT", 1)
+	if err != nil {
+		fatal(logger, "invalid configuration", err)
+	}
+	if minPeerCount < 0 {
+		fatal(logger, "invalid configuration", fmt.Errorf("MIN_PEER_COUNT must not be negative, got %d", minPeerCount))
+	}
+	thresholds := healthThresholds{
+		MaxBlockAge:  time.Duration(maxBlockAgeSeconds) * time.Second,
+		WarnBlockAge: time.Duration(warnBlockAgeSeconds) * time.Second,
+		MinPeerCount: uint64(minPeerCount),
+	}
+
+	cacheTTL, err := envDuration("SCRAPE_CACHE_TTL", 0)
+	if err != nil {
+		fatal(logger, "invalid configuration", err)
+	}
+
+	watchAddresses, err := parseAddresses(os.Getenv("WATCH_ADDRESSES"))
+	if err != nil {
+		fatal(logger, "invalid configuration", err)
+	}
+
+	extraLabels, err := parseLabels(os.Getenv("EXTRA_LABELS"))
+	if err != nil {
+		fatal(logger, "invalid configuration", fmt.Errorf("EXTRA_LABELS: %w", err))
+	}
+
+	var collectorOpts []collector.Option
+	if v := os.Getenv("EXPECTED_CHAIN_ID"); v != "" {
+		// accepts decimal ("2345") or hex ("0x929")
+		id, err := strconv.ParseUint(v, 0, 64)
+		if err != nil {
+			fatal(logger, "invalid configuration", fmt.Errorf("invalid EXPECTED_CHAIN_ID value %q: %w", v, err))
+		}
+		collectorOpts = append(collectorOpts, collector.WithExpectedChainID(id))
+	}
+
+	// TLS is optional but needs both halves of the key pair
+	tlsCertFile := os.Getenv("TLS_CERT_FILE")
+	tlsKeyFile := os.Getenv("TLS_KEY_FILE")
+	if (tlsCertFile == "") != (tlsKeyFile == "") {
+		fatal(logger, "invalid configuration", fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together"))
+	}
+	tlsEnabled := tlsCertFile != ""
+
+	// basic auth is optional but needs both user and password
+	authUser := os.Getenv("METRICS_AUTH_USER")
+	authPass := os.Getenv("METRICS_AUTH_PASS")
+	if (authUser == "") != (authPass == "") {
+		fatal(logger, "invalid configuration", fmt.Errorf("METRICS_AUTH_USER and METRICS_AUTH_PASS must be set together"))
+	}
+	authEnabled := authUser != ""
+
+	logger.Info("starting goat-monitor", "port", port, "tls", tlsEnabled, "basic_auth", authEnabled, "version", version, "commit", commit)
+	for _, endpoint := range rpcEndpoints {
+		logger.Info("monitoring RPC endpoint", "endpoint", rpc.RedactURL(endpoint))
+	}
+
+	// initialize one RPC client per endpoint, instrumented with latency
+	// metrics that persist across scrapes (see collector.RPCMetrics)
+	rpcMetrics := collector.NewRPCMetrics(extraLabels)
+	prometheus.MustRegister(rpcMetrics)
+	clientOpts := []rpc.Option{
+		rpc.WithLogger(logger),
+		rpc.WithTimeout(rpcTimeout),
+		rpc.WithRetry(maxAttempts, retryBaseDelay),
+	}
+	for key, value := range rpcHeaders {
+		clientOpts = append(clientOpts, rpc.WithHeader(key, value))
+	}
+	for method, d := range rpcMethodTimeouts {
+		clientOpts = append(clientOpts, rpc.WithMethodTimeout(method, d))
+	}
+	// each node's block watcher is shared with /health so both see the same
+	// stall state
+	nodes := make([]collector.Node, 0, len(rpcEndpoints))
+	for _, endpoint := range rpcEndpoints {
+		// the endpoint label is redacted: /metrics is unauthenticated by
+		// default, and API keys in the URL must not end up in the TSDB.
+		// the observer is per client so its metrics carry the label too
+		label := rpc.RedactURL(endpoint)
+		client := rpc.NewClientWithOptions(endpoint,
+			append(clientOpts, rpc.WithObserver(rpcMetrics.ForEndpoint(label)))...)
+		nodes = append(nodes, collector.NewNode(label, client))
+	}
 
 	// register Prometheus collector
-	goatCollector := collector.NewGoatCollector(client)
+	collectorOpts = append(collectorOpts,
+		collector.WithCacheTTL(cacheTTL),
+		collector.WithWatchAddresses(watchAddresses),
+		collector.WithConstLabels(extraLabels),
+	)
+	goatCollector := collector.NewGoatCollector(nodes, logger, collectorOpts...)
 	prometheus.MustRegister(goatCollector)
+	prometheus.MustRegister(collector.NewBuildInfoCollector(version, commit, extraLabels))
+
+	// one-shot mode: a single round of queries, no server. subscriptions are
+	// not started, so WebSocket nodes are polled like HTTP ones.
+	if oneShot {
+		os.Exit(runCheck(os.Stdout, goatCollector, thresholds))
+	}
+
+	// stop on SIGINT/SIGTERM so in-flight scrapes can drain during rollouts
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// ws:// and wss:// endpoints push new heads instead of being polled for
+	// them; the subscription reconnects on its own until shutdown
+	for _, n := range nodes {
+		if hs, ok := n.Client.(collector.HeadSubscriber); ok && n.Heads != nil {
+			go hs.SubscribeNewHeads(ctx, n.Heads.Update, n.Heads.Reset)
+		}
+	}
+
+	// optionally exercise the collector once before serving traffic so
+	// misconfiguration surfaces at startup rather than on the first scrape
+	if selfTest {
+		if err := runSelfTest(logger, goatCollector, selfTestStrict); err != nil {
+			fatal(logger, "aborting startup", err)
+		}
+	}
 
 	// HTTP routes
 	mux := http.NewServeMux()
@@ -81,7 +289,17 @@ func main() {
 
 	// JSON health dashboard
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		healthHandler(w, r, client, rpcEndpoint)
+		healthHandler(w, r, goatCollector, thresholds)
+	})
+
+	// the same data as /metrics, as flat JSON for non-Prometheus consumers
+	mux.HandleFunc("/metrics-json", func(w http.ResponseWriter, r *http.Request) {
+		metricsJSONHandler(w, r, goatCollector)
+	})
+
+	// readiness: stricter than /health, requires the node to be synced
+	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		readyHandler(w, r, goatCollector)
 	})
 
 	// root redirects to /health
@@ -93,74 +311,259 @@ func main() {
 		http.Redirect(w, r, "/health", http.StatusTemporaryRedirect)
 	})
 
+	// optionally require credentials on every route, including the redirect
+	var handler http.Handler = mux
+	if authEnabled {
+		handler = basicAuth(mux, authUser, authPass)
+	}
+
+	// liveness only tells whether this process is serving, never the node's
+	// state: restarting the monitor can't fix a stalled node, and would
+	// reset the block watcher and hide the stall. it reveals nothing, so it
+	// stays reachable without credentials for the kubelet.
+	root := http.NewServeMux()
+	root.Handle("/", handler)
+	root.HandleFunc("/livez", livezHandler)
+
+	// a scrape or health check must be able to outlast a full round of
+	// timed-out, retried RPC calls, or the server would cut the response
+	// off just before the slow snapshot completes
+	writeTimeout := 15 * time.Second
+	if d := goatCollector.MaxSnapshotDuration() + 5*time.Second; d > writeTimeout {
+		writeTimeout = d
+		logger.Info("raised HTTP write timeout to fit RPC timeouts and retries", "write_timeout", writeTimeout)
+	}
+
 	// start server
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%s", port),
-		Handler:      mux,
+		Handler:      root,
 		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
+		WriteTimeout: writeTimeout,
 		IdleTimeout:  60 * time.Second,
 	}
 
-	if err := server.ListenAndServe(); err != nil {
-		log.Fatalf("server failed: %v", err)
+	serverErr := make(chan error, 1)
+	go func() {
+		if tlsEnabled {
+			serverErr <- server.ListenAndServeTLS(tlsCertFile, tlsKeyFile)
+			return
+		}
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		fatal(logger, "server failed", err)
+	case <-ctx.Done():
+	}
+
+	logger.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownGracePeriod)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		fatal(logger, "graceful shutdown failed", err)
 	}
 }
 
-// healthHandler queries the RPC node and returns a JSON health response.
-func healthHandler(w http.ResponseWriter, _ *http.Request, client *rpc.Client, endpoint string) {
-	resp := healthResponse{
-		Status:       "ok",
-		NodeEndpoint: endpoint,
-		Timestamp:    time.Now().UTC().Format(time.RFC3339),
+// newLogger builds the process logger. level is one of debug, info, warn or
+// error (default info); format is "json" or "text" (default text).
+func newLogger(level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if level != "" {
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("LOG_LEVEL: %w", err)
+		}
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch strings.ToLower(format) {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(os.Stderr, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts)), nil
+	default:
+		return nil, fmt.Errorf("LOG_FORMAT: unknown format %q (want text or json)", format)
 	}
+}
+
+// fatal logs err at ERROR level and exits with a non-zero status.
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
+}
 
-	// fetch block height
-	block, err := client.GetBlockNumber()
+// envBool reads a boolean environment variable, returning def if unset.
+func envBool(name string, def bool) (bool, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(v)
 	if err != nil {
-		resp.Status = "degraded"
-		resp.Error = fmt.Sprintf("block number: %v", err)
+		return false, fmt.Errorf("invalid %s value %q: %w", name, v, err)
 	}
-	resp.BlockHeight = block
+	return b, nil
+}
+
+// envInt reads an integer environment variable, returning def if unset.
+func envInt(name string, def int) (int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value %q: %w", name, v, err)
+	}
+	return n, nil
+}
 
-	// fetch chain ID
-	chainID, err := client.GetChainID()
+// envDuration reads a time.Duration environment variable (e.g. "250ms"),
+// returning def if unset.
+func envDuration(name string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
 	if err != nil {
-		resp.Status = "degraded"
-		if resp.Error != "" {
-			resp.Error += "; "
+		return 0, fmt.Errorf("invalid %s value %q: %w", name, v, err)
+	}
+	return d, nil
+}
+
+// parseKeyValues parses a comma-separated list of key=value pairs, e.g.
+// "Authorization=Bearer abc,X-Api-Key=123". only the first "=" in each pair
+// separates key from value, so values may themselves contain "=".
+func parseKeyValues(spec string) (map[string]string, error) {
+	pairs := make(map[string]string)
+	if strings.TrimSpace(spec) == "" {
+		return pairs, nil
+	}
+	for _, item := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(item, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("malformed pair %q (want key=value)", item)
 		}
-		resp.Error += fmt.Sprintf("chain id: %v", err)
+		pairs[key] = strings.TrimSpace(value)
 	}
-	resp.ChainID = chainID
+	return pairs, nil
+}
 
-	// fetch sync status
-	syncing, progress, err := client.GetSyncStatus()
+// parseMethodTimeouts parses a comma-separated list of method=duration
+// pairs, e.g. "eth_syncing=30s,eth_getBalance=2s".
+func parseMethodTimeouts(spec string) (map[string]time.Duration, error) {
+	pairs, err := parseKeyValues(spec)
 	if err != nil {
-		resp.Status = "degraded"
-		if resp.Error != "" {
-			resp.Error += "; "
+		return nil, err
+	}
+
+	timeouts := make(map[string]time.Duration, len(pairs))
+	for method, value := range pairs {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid timeout %q for method %s (want a positive duration, e.g. 30s)", value, method)
 		}
-		resp.Error += fmt.Sprintf("sync status: %v", err)
+		timeouts[method] = d
+	}
+	return timeouts, nil
+}
+
+// labelNamePattern matches a valid Prometheus label name.
+var labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// reservedLabels are label names already used by the exporter's metrics.
+var reservedLabels = map[string]bool{
+	"endpoint": true,
+	"address":  true,
+	"method":   true,
+	// goat_rpc_errors_total
+	"type": true,
+	// bucket bound of goat_rpc_request_duration_seconds; the histogram
+	// panics on first use if it is also a const label
+	"le": true,
+	// goat_monitor_build_info
+	"version":    true,
+	"commit":     true,
+	"go_version": true,
+}
+
+// parseLabels parses a comma-separated list of static labels, e.g.
+// "env=prod,network=goat-mainnet", rejecting invalid or reserved names and
+// empty values.
+func parseLabels(spec string) (prometheus.Labels, error) {
+	pairs, err := parseKeyValues(spec)
+	if err != nil {
+		return nil, err
 	}
-	resp.Syncing = syncing
 
-	if progress != nil {
-		resp.SyncProgress = &syncProgress{
-			StartingBlock: progress.StartingBlock,
-			CurrentBlock:  progress.CurrentBlock,
-			HighestBlock:  progress.HighestBlock,
+	labels := make(prometheus.Labels, len(pairs))
+	for name, value := range pairs {
+		if !labelNamePattern.MatchString(name) || strings.HasPrefix(name, "__") {
+			return nil, fmt.Errorf("invalid label name %q", name)
+		}
+		if reservedLabels[name] {
+			return nil, fmt.Errorf("label name %q is reserved", name)
 		}
+		if value == "" {
+			return nil, fmt.Errorf("label %q has an empty value", name)
+		}
+		labels[name] = value
 	}
+	return labels, nil
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	if resp.Status != "ok" {
-		w.WriteHeader(http.StatusServiceUnavailable)
+// addressPattern matches a 0x-prefixed 20-byte hex account address.
+var addressPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
+// parseAddresses splits a comma-separated list of account addresses,
+// rejecting malformed and duplicate entries. addresses are lowercased so the
+// metric label is stable regardless of checksum casing.
+func parseAddresses(spec string) ([]string, error) {
+	var addresses []string
+	seen := make(map[string]bool)
+	for _, addr := range strings.Split(spec, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		if !addressPattern.MatchString(addr) {
+			return nil, fmt.Errorf("invalid address in WATCH_ADDRESSES: %q (want 0x + 40 hex chars)", addr)
+		}
+		addr = strings.ToLower(addr)
+		if seen[addr] {
+			return nil, fmt.Errorf("duplicate address in WATCH_ADDRESSES: %s", addr)
+		}
+		seen[addr] = true
+		addresses = append(addresses, addr)
 	}
+	return addresses, nil
+}
 
-	enc := json.NewEncoder(w)
-	enc.SetIndent("", "  ")
-	if err := enc.Encode(resp); err != nil {
-		log.Printf("error encoding health response: %v", err)
+// parseEndpoints splits a comma-separated list of RPC endpoints, rejecting
+// an empty list and duplicates (which would produce colliding labels).
+// endpoints are compared, and reported, in their redacted form, since that
+// is what the label holds.
+func parseEndpoints(spec string) ([]string, error) {
+	var endpoints []string
+	seen := make(map[string]bool)
+	for _, endpoint := range strings.Split(spec, ",") {
+		endpoint = strings.TrimSpace(endpoint)
+		if endpoint == "" {
+			continue
+		}
+		label := rpc.RedactURL(endpoint)
+		if seen[label] {
+			return nil, fmt.Errorf("duplicate endpoint in GOAT_RPC_NODE: %s (endpoints differing only in credentials are not supported)", label)
+		}
+		seen[label] = true
+		endpoints = append(endpoints, endpoint)
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("GOAT_RPC_NODE environment variable is required")
 	}
+	return endpoints, nil
 }
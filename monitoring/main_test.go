@@ -0,0 +1,75 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestParseLabels(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    prometheus.Labels
+		wantErr bool
+	}{
+		{name: "unset", spec: "", want: prometheus.Labels{}},
+		{name: "single label", spec: "cluster=prod", want: prometheus.Labels{"cluster": "prod"}},
+		{name: "several labels, spaces trimmed", spec: " cluster = prod , region=eu-west-1", want: prometheus.Labels{"cluster": "prod", "region": "eu-west-1"}},
+		{name: "value containing =", spec: "selector=app=goat", want: prometheus.Labels{"selector": "app=goat"}},
+		{name: "malformed pair", spec: "cluster", wantErr: true},
+		{name: "invalid name", spec: "1cluster=prod", wantErr: true},
+		{name: "invalid character in name", spec: "clus-ter=prod", wantErr: true},
+		{name: "double-underscore name", spec: "__name__=x", wantErr: true},
+		{name: "reserved endpoint", spec: "endpoint=x", wantErr: true},
+		{name: "reserved type", spec: "type=x", wantErr: true},
+		{name: "reserved le", spec: "le=x", wantErr: true},
+		{name: "empty value", spec: "cluster=", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLabels(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLabels(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if !tt.wantErr && !maps.Equal(got, tt.want) {
+				t.Errorf("parseLabels(%q) = %v, want %v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEndpoints(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "single", spec: "http://geth:8545", want: []string{"http://geth:8545"}},
+		{name: "several, blanks skipped", spec: "http://a:8545, ,ws://b:8546", want: []string{"http://a:8545", "ws://b:8546"}},
+		{name: "credentials kept for the client", spec: "https://rpc.example.com/?apikey=k", want: []string{"https://rpc.example.com/?apikey=k"}},
+		{name: "empty", spec: " , ", wantErr: true},
+		{name: "duplicate", spec: "http://a:8545,http://a:8545", wantErr: true},
+		{name: "duplicate once redacted", spec: "https://rpc.example.com/?apikey=k1,https://rpc.example.com/?apikey=k2", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEndpoints(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseEndpoints(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if err != nil && strings.Contains(err.Error(), "apikey") {
+				t.Errorf("error %q leaks the query string", err)
+			}
+			if !tt.wantErr && !slices.Equal(got, tt.want) {
+				t.Errorf("parseEndpoints(%q) = %v, want %v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
@@ -4,18 +4,50 @@ package rpc
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"math/big"
+	"math/rand"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
+// defaultTimeout bounds each round trip unless overridden by WithTimeout or
+// WithMethodTimeout.
+const defaultTimeout = 10 * time.Second
+
 // Client is a JSON-RPC client for an EVM-compatible node.
 type Client struct {
 	endpoint   string
+	redacted   string // endpoint as it may appear in logs and errors
 	httpClient *http.Client
+	timeout    time.Duration
+	headers    http.Header
+	observer   Observer
+	logger     *slog.Logger
+
+	// per-method overrides of timeout, see WithMethodTimeout
+	methodTimeouts map[string]time.Duration
+
+	// retry policy; maxAttempts=1 disables retries
+	maxAttempts    int
+	retryBaseDelay time.Duration
+
+	// ws is set for ws:// and wss:// endpoints, which are queried over a
+	// single WebSocket connection instead of HTTP
+	ws *wsTransport
+
+	// lastID is incremented for every request sent, so each request carries
+	// a unique ID that its response must echo back
+	lastID atomic.Uint64
 }
 
 // SyncProgress holds the sync status fields returned by eth_syncing.
@@ -30,35 +62,60 @@ type jsonRPCRequest struct {
 	JSONRPC string        `json:"jsonrpc"`
 	Method  string        `json:"method"`
 	Params  []interface{} `json:"params"`
-	ID      int           `json:"id"`
+	ID      uint64        `json:"id"`
 }
 
 // jsonRPCResponse represents a JSON-RPC 2.0 response.
 type jsonRPCResponse struct {
 	JSONRPC string          `json:"jsonrpc"`
 	Result  json.RawMessage `json:"result"`
-	Error   *jsonRPCError   `json:"error,omitempty"`
-	ID      int             `json:"id"`
-}
-
-// jsonRPCError represents a JSON-RPC 2.0 error object.
-type jsonRPCError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Error   *RPCError       `json:"error,omitempty"`
+	// ID is kept raw since servers may echo it as a number or a string
+	ID json.RawMessage `json:"id"`
 }
 
 // NewClient creates a new RPC client for the given endpoint URL.
 func NewClient(endpoint string) *Client {
-	return &Client{
-		endpoint: endpoint,
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+	return NewClientWithOptions(endpoint)
+}
+
+// NewClientWithOptions creates a new RPC client for the given endpoint URL,
+// applying the given options on top of the defaults.
+func NewClientWithOptions(endpoint string, opts ...Option) *Client {
+	c := &Client{
+		endpoint:    endpoint,
+		redacted:    RedactURL(endpoint),
+		timeout:     defaultTimeout,
+		headers:     make(http.Header),
+		maxAttempts: 1,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	// no client-level timeout: each request carries its own context
+	// deadline so that per-method overrides can exceed the default
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{}
+	}
+	if c.logger == nil {
+		c.logger = slog.Default()
+	}
+	c.logger = c.logger.With("endpoint", c.redacted)
+	if isWebSocketURL(endpoint) {
+		c.ws = newWSTransport(endpoint, c.headers, c.logger)
+	}
+	return c
 }
 
 // call executes a JSON-RPC method and returns the raw result.
 func (c *Client) call(method string, params ...interface{}) (json.RawMessage, error) {
+	if c.observer != nil {
+		start := time.Now()
+		defer func() {
+			c.observer.ObserveRequest(method, time.Since(start))
+		}()
+	}
+
 	if params == nil {
 		params = []interface{}{}
 	}
@@ -67,39 +124,210 @@ func (c *Client) call(method string, params ...interface{}) (json.RawMessage, er
 		JSONRPC: "2.0",
 		Method:  method,
 		Params:  params,
-		ID:      1,
 	}
 
+	for attempt := 1; ; attempt++ {
+		result, retryable, err := c.sendWithTimeout(req)
+		if err == nil {
+			return result, nil
+		}
+		if !retryable || attempt >= c.maxAttempts {
+			// an unsupported method is a property of the node, not a
+			// failure; callers either fall back or skip the metric, so
+			// logging it on every scrape would only be noise
+			level := slog.LevelWarn
+			if errors.Is(err, ErrMethodNotFound) {
+				level = slog.LevelDebug
+			}
+			c.logger.Log(context.Background(), level, "RPC call failed", "method", method, "attempts", attempt, "error", err)
+			if c.observer != nil {
+				c.observer.ObserveError(method, err)
+			}
+			return nil, err
+		}
+
+		c.logger.Debug("retrying RPC call", "method", method, "attempt", attempt, "error", err)
+		if c.observer != nil {
+			c.observer.ObserveRetry(method)
+		}
+		time.Sleep(c.backoff(attempt))
+	}
+}
+
+// timeoutFor returns the round-trip timeout for method.
+func (c *Client) timeoutFor(method string) time.Duration {
+	if d, ok := c.methodTimeouts[method]; ok {
+		return d
+	}
+	return c.timeout
+}
+
+// MaxCallDuration returns the longest a call to method can take: every
+// attempt running into the method's timeout, plus the longest possible
+// backoff between attempts.
+func (c *Client) MaxCallDuration(method string) time.Duration {
+	d := time.Duration(c.maxAttempts) * c.timeoutFor(method)
+	for attempt := 1; attempt < c.maxAttempts; attempt++ {
+		d += c.retryBaseDelay << (attempt - 1)
+	}
+	return d
+}
+
+// sendWithTimeout performs a single attempt of req, bounded by the method's
+// timeout.
+func (c *Client) sendWithTimeout(req jsonRPCRequest) (result json.RawMessage, retryable bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeoutFor(req.Method))
+	defer cancel()
+	return c.send(ctx, req)
+}
+
+// send performs a single round trip for a JSON-RPC request, assigning it a
+// fresh ID. transport failures are returned as *TransportError and error
+// objects from the node as *RPCError. retryable reports whether the failure
+// is transient (connection errors, HTTP 5xx and 429); JSON-RPC error objects
+// are deterministic and are never retried.
+func (c *Client) send(ctx context.Context, req jsonRPCRequest) (result json.RawMessage, retryable bool, err error) {
+	req.ID = c.lastID.Add(1)
 	body, err := json.Marshal(req)
 	if err != nil {
-		return nil, fmt.Errorf("marshal request: %w", err)
+		return nil, false, fmt.Errorf("marshal request: %w", err)
 	}
 
-	resp, err := c.httpClient.Post(c.endpoint, "application/json", bytes.NewReader(body))
+	var rpcResp *jsonRPCResponse
+	if c.ws != nil {
+		rpcResp, retryable, err = c.ws.roundTrip(ctx, req.ID, body, nil)
+	} else {
+		rpcResp, retryable, err = c.postHTTP(ctx, body)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("RPC request to %s: %w", c.endpoint, err)
+		return nil, retryable, err
+	}
+
+	// a mismatched ID means the response isn't ours (e.g. a misbehaving
+	// proxy serving a cached reply). error objects may carry a null ID when
+	// the server couldn't parse the request.
+	if !idMatches(rpcResp.ID, req.ID) && !(rpcResp.Error != nil && isNullID(rpcResp.ID)) {
+		return nil, false, fmt.Errorf("response ID %s does not match request ID %d", string(rpcResp.ID), req.ID)
+	}
+
+	if rpcResp.Error != nil {
+		return nil, false, rpcResp.Error
+	}
+
+	return rpcResp.Result, false, nil
+}
+
+// postHTTP POSTs an encoded request to the endpoint and decodes the response.
+func (c *Client) postHTTP(ctx context.Context, body []byte) (rpcResp *jsonRPCResponse, retryable bool, err error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, false, fmt.Errorf("build request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	for key, values := range c.headers {
+		httpReq.Header[key] = values
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		// *url.Error repeats the full URL, query string included
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = c.redacted
+		}
+		return nil, true, &TransportError{Err: fmt.Errorf("RPC request to %s: %w", c.redacted, err)}
 	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("read response body: %w", err)
+		return nil, true, &TransportError{Err: fmt.Errorf("read response body: %w", err)}
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("RPC returned HTTP %d: %s", resp.StatusCode, string(respBody))
+		retryable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return nil, retryable, &TransportError{
+			StatusCode: resp.StatusCode,
+			Err:        fmt.Errorf("RPC returned HTTP %d: %s", resp.StatusCode, bodySnippet(respBody)),
+		}
 	}
 
-	var rpcResp jsonRPCResponse
-	if err := json.Unmarshal(respBody, &rpcResp); err != nil {
-		return nil, fmt.Errorf("unmarshal response: %w", err)
+	// gateways in front of the node sometimes answer with an empty body or
+	// an HTML error page; include what actually came back so it's visible
+	// in logs instead of a bare "invalid character '<'"
+	contentType := resp.Header.Get("Content-Type")
+	if len(bytes.TrimSpace(respBody)) == 0 {
+		return nil, false, fmt.Errorf("empty response body (HTTP %d, Content-Type %q)", resp.StatusCode, contentType)
 	}
 
-	if rpcResp.Error != nil {
-		return nil, fmt.Errorf("RPC error %d: %s", rpcResp.Error.Code, rpcResp.Error.Message)
+	rpcResp = &jsonRPCResponse{}
+	if err := json.Unmarshal(respBody, rpcResp); err != nil {
+		return nil, false, fmt.Errorf("unmarshal response (HTTP %d, Content-Type %q, body %q): %w",
+			resp.StatusCode, contentType, bodySnippet(respBody), err)
+	}
+
+	return rpcResp, false, nil
+}
+
+// maxBodySnippet is the number of response body bytes included in errors.
+const maxBodySnippet = 200
+
+// bodySnippet returns the start of a response body for use in error
+// messages, with whitespace collapsed and long bodies truncated.
+func bodySnippet(body []byte) string {
+	s := strings.Join(strings.Fields(string(body)), " ")
+	if len(s) > maxBodySnippet {
+		return s[:maxBodySnippet] + "..."
+	}
+	return s
+}
+
+// idMatches reports whether a raw response ID equals the request ID, whether
+// it was echoed as a JSON number or a string.
+func idMatches(raw json.RawMessage, id uint64) bool {
+	var n uint64
+	if err := json.Unmarshal(raw, &n); err == nil {
+		return n == id
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s == strconv.FormatUint(id, 10)
+	}
+	return false
+}
+
+// RedactURL returns endpoint without the credentials it may carry, in its
+// userinfo ("user:pass@") or query string ("?apikey=..."), for use in
+// metric labels, logs and error messages. an unparseable endpoint is
+// replaced entirely, since there is no telling where a secret might be.
+func RedactURL(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "invalid-url"
 	}
+	u.User = nil
+	u.RawQuery = ""
+	u.ForceQuery = false
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String()
+}
+
+// isNullID reports whether a raw response ID is absent or JSON null.
+func isNullID(raw json.RawMessage) bool {
+	return len(raw) == 0 || string(bytes.TrimSpace(raw)) == "null"
+}
 
-	return rpcResp.Result, nil
+// backoff returns the delay before the next attempt: exponential in the
+// number of attempts made so far, with "equal jitter" (a random value in
+// [d/2, d]) so that many monitors don't retry against a node in lockstep.
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.retryBaseDelay << (attempt - 1)
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
 }
 
 // GetBlockNumber returns the current block height (eth_blockNumber).
@@ -132,6 +360,139 @@ func (c *Client) GetChainID() (uint64, error) {
 	return parseHexUint64(hexChainID)
 }
 
+// GetPeerCount returns the number of peers connected to the node (net_peerCount).
+func (c *Client) GetPeerCount() (uint64, error) {
+	result, err := c.call("net_peerCount")
+	if err != nil {
+		return 0, err
+	}
+
+	var hexPeers string
+	if err := json.Unmarshal(result, &hexPeers); err != nil {
+		return 0, fmt.Errorf("unmarshal peer count: %w", err)
+	}
+
+	return parseHexUint64(hexPeers)
+}
+
+// GetGasPrice returns the current gas price in wei (eth_gasPrice).
+// the value is returned as a big.Int since it is not bounded by uint64.
+func (c *Client) GetGasPrice() (*big.Int, error) {
+	result, err := c.call("eth_gasPrice")
+	if err != nil {
+		return nil, err
+	}
+
+	var hexPrice string
+	if err := json.Unmarshal(result, &hexPrice); err != nil {
+		return nil, fmt.Errorf("unmarshal gas price: %w", err)
+	}
+
+	return parseHexBigInt(hexPrice)
+}
+
+// GetMaxPriorityFeePerGas returns the node's suggested EIP-1559 priority
+// fee (tip) in wei (eth_maxPriorityFeePerGas).
+func (c *Client) GetMaxPriorityFeePerGas() (*big.Int, error) {
+	result, err := c.call("eth_maxPriorityFeePerGas")
+	if err != nil {
+		return nil, err
+	}
+
+	var hexFee string
+	if err := json.Unmarshal(result, &hexFee); err != nil {
+		return nil, fmt.Errorf("unmarshal priority fee: %w", err)
+	}
+
+	return parseHexBigInt(hexFee)
+}
+
+// GetFeeHistoryReward returns the priority fee paid at the given percentile
+// (0-100) of gas used in the latest block, in wei
+// (eth_feeHistory(1, "latest", [percentile])).
+func (c *Client) GetFeeHistoryReward(percentile float64) (*big.Int, error) {
+	result, err := c.call("eth_feeHistory", "0x1", "latest", []float64{percentile})
+	if err != nil {
+		return nil, err
+	}
+
+	var history struct {
+		Reward [][]string `json:"reward"`
+	}
+	if err := json.Unmarshal(result, &history); err != nil {
+		return nil, fmt.Errorf("unmarshal fee history: %w", err)
+	}
+	if len(history.Reward) == 0 || len(history.Reward[0]) == 0 {
+		return nil, fmt.Errorf("fee history has no reward data")
+	}
+
+	return parseHexBigInt(history.Reward[0][0])
+}
+
+// GetLatestBlockTimestamp returns the unix timestamp of the latest block
+// (eth_getBlockByNumber("latest", false)). it returns ErrBlockNotFound if
+// the node has no block yet.
+func (c *Client) GetLatestBlockTimestamp() (uint64, error) {
+	result, err := c.call("eth_getBlockByNumber", "latest", false)
+	if err != nil {
+		return 0, err
+	}
+
+	var block *struct {
+		Timestamp string `json:"timestamp"`
+	}
+	if err := json.Unmarshal(result, &block); err != nil {
+		return 0, fmt.Errorf("unmarshal block: %w", err)
+	}
+	if block == nil {
+		return 0, ErrBlockNotFound
+	}
+
+	return parseHexUint64(block.Timestamp)
+}
+
+// GetBalance returns the balance of address in wei at the given block
+// ("latest", "pending", or a hex block number) (eth_getBalance).
+func (c *Client) GetBalance(address string, block string) (*big.Int, error) {
+	result, err := c.call("eth_getBalance", address, block)
+	if err != nil {
+		return nil, err
+	}
+
+	var hexBalance string
+	if err := json.Unmarshal(result, &hexBalance); err != nil {
+		return nil, fmt.Errorf("unmarshal balance: %w", err)
+	}
+
+	return parseHexBigInt(hexBalance)
+}
+
+// GetTxpoolStatus returns the number of pending and queued transactions in
+// the node's mempool (txpool_status). txpool is a non-standard namespace that
+// many nodes disable; check for that with errors.Is(err, ErrMethodNotFound).
+func (c *Client) GetTxpoolStatus() (pending uint64, queued uint64, err error) {
+	result, err := c.call("txpool_status")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var status struct {
+		Pending json.RawMessage `json:"pending"`
+		Queued  json.RawMessage `json:"queued"`
+	}
+	if err := json.Unmarshal(result, &status); err != nil {
+		return 0, 0, fmt.Errorf("unmarshal txpool status: %w", err)
+	}
+
+	if pending, err = parseQuantity(status.Pending); err != nil {
+		return 0, 0, fmt.Errorf("txpool pending: %w", err)
+	}
+	if queued, err = parseQuantity(status.Queued); err != nil {
+		return 0, 0, fmt.Errorf("txpool queued: %w", err)
+	}
+	return pending, queued, nil
+}
+
 // GetSyncStatus returns whether the node is syncing and its progress.
 // if the node is fully synced, syncing=false and progress=nil.
 // if the node is syncing, syncing=true and progress contains the details.
@@ -148,26 +509,43 @@ func (c *Client) GetSyncStatus() (bool, *SyncProgress, error) {
 		return false, nil, nil
 	}
 
-	// parse as sync progress object
-	var rawProgress map[string]string
+	// parse as sync progress object. values are decoded lazily since some
+	// clients and proxies return bare JSON numbers instead of hex strings;
+	// fields other than the three below are ignored.
+	var rawProgress map[string]json.RawMessage
 	if err := json.Unmarshal(result, &rawProgress); err != nil {
 		return false, nil, fmt.Errorf("unmarshal sync progress: %w", err)
 	}
 
 	progress := &SyncProgress{}
 	if v, ok := rawProgress["startingBlock"]; ok {
-		progress.StartingBlock, _ = parseHexUint64(v)
+		progress.StartingBlock, _ = parseQuantity(v)
 	}
 	if v, ok := rawProgress["currentBlock"]; ok {
-		progress.CurrentBlock, _ = parseHexUint64(v)
+		progress.CurrentBlock, _ = parseQuantity(v)
 	}
 	if v, ok := rawProgress["highestBlock"]; ok {
-		progress.HighestBlock, _ = parseHexUint64(v)
+		progress.HighestBlock, _ = parseQuantity(v)
 	}
 
 	return true, progress, nil
 }
 
+// parseQuantity decodes a JSON quantity that is either a 0x-prefixed hex
+// string (the JSON-RPC convention) or a bare JSON number.
+func parseQuantity(raw json.RawMessage) (uint64, error) {
+	var hex string
+	if err := json.Unmarshal(raw, &hex); err == nil {
+		return parseHexUint64(hex)
+	}
+
+	var n uint64
+	if err := json.Unmarshal(raw, &n); err != nil {
+		return 0, fmt.Errorf("invalid quantity: %s", string(raw))
+	}
+	return n, nil
+}
+
 // parseHexUint64 converts a hex string (0x-prefixed) to uint64.
 func parseHexUint64(hex string) (uint64, error) {
 	n := new(big.Int)
@@ -177,6 +555,15 @@ func parseHexUint64(hex string) (uint64, error) {
 	return n.Uint64(), nil
 }
 
+// parseHexBigInt converts a hex string (0x-prefixed) to a big.Int.
+func parseHexBigInt(hex string) (*big.Int, error) {
+	n := new(big.Int)
+	if _, ok := n.SetString(stripHexPrefix(hex), 16); !ok {
+		return nil, fmt.Errorf("invalid hex value: %s", hex)
+	}
+	return n, nil
+}
+
 // stripHexPrefix removes the "0x" or "0X" prefix from a hex string.
 func stripHexPrefix(s string) string {
 	if len(s) >= 2 && (s[:2] == "0x" || s[:2] == "0X") {
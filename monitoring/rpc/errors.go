@@ -0,0 +1,50 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrBlockNotFound is returned when the node has no block to report, e.g.
+// eth_getBlockByNumber returned null on a fresh chain.
+var ErrBlockNotFound = errors.New("block not found")
+
+// ErrMethodNotFound matches (via errors.Is) an *RPCError with code -32601,
+// returned by nodes that don't implement or have disabled a method.
+var ErrMethodNotFound = errors.New("method not found")
+
+// CodeMethodNotFound is the JSON-RPC 2.0 "method not found" error code.
+const CodeMethodNotFound = -32601
+
+// RPCError is a JSON-RPC 2.0 error object returned by the node. the request
+// reached the node and was rejected, so retrying it won't help.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("RPC error %d: %s", e.Code, e.Message)
+}
+
+// Is lets callers test for ErrMethodNotFound with errors.Is.
+func (e *RPCError) Is(target error) bool {
+	return target == ErrMethodNotFound && e.Code == CodeMethodNotFound
+}
+
+// TransportError is returned when a request never got a JSON-RPC answer:
+// the connection failed, the server replied with a non-200 HTTP status, or
+// a WebSocket dropped mid-call. StatusCode is set only in the HTTP status
+// case.
+type TransportError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *TransportError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,86 @@
+package rpc
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRPCErrorAs(t *testing.T) {
+	c := newTestClient(t, func(string, uint64) string {
+		return `{"jsonrpc":"2.0","id":null,"error":{"code":-32601,"message":"the method txpool_status does not exist/is not available"}}`
+	})
+
+	_, _, err := c.GetTxpoolStatus()
+
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) {
+		t.Fatalf("errors.As(%v, *RPCError) = false", err)
+	}
+	if rpcErr.Code != CodeMethodNotFound {
+		t.Errorf("Code = %d, want %d", rpcErr.Code, CodeMethodNotFound)
+	}
+	if !errors.Is(err, ErrMethodNotFound) {
+		t.Errorf("errors.Is(%v, ErrMethodNotFound) = false", err)
+	}
+
+	var transportErr *TransportError
+	if errors.As(err, &transportErr) {
+		t.Errorf("JSON-RPC error also matched *TransportError")
+	}
+}
+
+func TestRPCErrorOtherCodeIsNotMethodNotFound(t *testing.T) {
+	c := newTestClient(t, func(string, uint64) string {
+		return `{"jsonrpc":"2.0","id":null,"error":{"code":-32000,"message":"header not found"}}`
+	})
+
+	_, err := c.GetBlockNumber()
+	if errors.Is(err, ErrMethodNotFound) {
+		t.Errorf("errors.Is(%v, ErrMethodNotFound) = true for code -32000", err)
+	}
+}
+
+func TestTransportErrorAs(t *testing.T) {
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachableURL := unreachable.URL
+	unreachable.Close()
+
+	badGateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "<html>bad gateway</html>", http.StatusBadGateway)
+	}))
+	defer badGateway.Close()
+
+	tests := []struct {
+		name           string
+		endpoint       string
+		wantStatusCode int
+	}{
+		{"connection refused", unreachableURL, 0},
+		{"HTTP error status", badGateway.URL, http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClientWithOptions(tt.endpoint, WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+
+			_, err := c.GetBlockNumber()
+
+			var transportErr *TransportError
+			if !errors.As(err, &transportErr) {
+				t.Fatalf("errors.As(%v, *TransportError) = false", err)
+			}
+			if transportErr.StatusCode != tt.wantStatusCode {
+				t.Errorf("StatusCode = %d, want %d", transportErr.StatusCode, tt.wantStatusCode)
+			}
+
+			var rpcErr *RPCError
+			if errors.As(err, &rpcErr) {
+				t.Errorf("transport error also matched *RPCError")
+			}
+		})
+	}
+}
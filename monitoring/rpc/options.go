@@ -0,0 +1,96 @@
+package rpc
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Option configures a Client created with NewClientWithOptions.
+type Option func(*Client)
+
+// Observer receives instrumentation events from the client.
+// implementations must be safe for concurrent use.
+type Observer interface {
+	// ObserveRequest records the wall-clock duration of an RPC call.
+	ObserveRequest(method string, duration time.Duration)
+
+	// ObserveRetry records that a failed call is about to be retried.
+	ObserveRetry(method string)
+
+	// ObserveError records that a call failed after all attempts, with the
+	// final error (a *TransportError, an *RPCError, or a malformed response).
+	ObserveError(method string, err error)
+}
+
+// WithObserver registers an Observer that is notified after every call.
+func WithObserver(o Observer) Option {
+	return func(c *Client) {
+		c.observer = o
+	}
+}
+
+// WithTimeout sets the default timeout for each round trip (default 10s),
+// enforced as a per-request context deadline. a client supplied with
+// WithHTTPClient may still impose a shorter timeout of its own. there is no
+// way to disable the deadline: non-positive values keep the default rather
+// than failing every call at once.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		if d > 0 {
+			c.timeout = d
+		}
+	}
+}
+
+// WithMethodTimeout overrides the round-trip timeout for a single method,
+// e.g. a generous limit for eth_syncing on a node under heavy sync. other
+// methods keep the default set by WithTimeout.
+func WithMethodTimeout(method string, d time.Duration) Option {
+	return func(c *Client) {
+		if c.methodTimeouts == nil {
+			c.methodTimeouts = make(map[string]time.Duration)
+		}
+		c.methodTimeouts[method] = d
+	}
+}
+
+// WithHTTPClient uses the given HTTP client for all requests, e.g. to supply
+// a custom transport or TLS configuration. it is ignored for ws:// and
+// wss:// endpoints.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithHeader adds a header that is sent with every request, e.g. an
+// Authorization header for an authenticated RPC gateway. for WebSocket
+// endpoints it is sent with the opening handshake.
+func WithHeader(key, value string) Option {
+	return func(c *Client) {
+		c.headers.Set(key, value)
+	}
+}
+
+// WithRetry retries transient failures (connection errors, HTTP 5xx and 429)
+// up to maxAttempts total attempts, waiting an exponentially increasing,
+// jittered delay starting at baseDelay between attempts. JSON-RPC error
+// responses are never retried. maxAttempts below 1 is treated as 1.
+func WithRetry(maxAttempts int, baseDelay time.Duration) Option {
+	return func(c *Client) {
+		if maxAttempts < 1 {
+			maxAttempts = 1
+		}
+		c.maxAttempts = maxAttempts
+		c.retryBaseDelay = baseDelay
+	}
+}
+
+// WithLogger sets the logger used for RPC errors and retries. the client
+// tags every record with its endpoint. defaults to slog.Default().
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Client) {
+		c.logger = l
+	}
+}
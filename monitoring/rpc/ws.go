@@ -0,0 +1,387 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// bounds on the delay between newHeads resubscription attempts
+	minResubscribeDelay = time.Second
+	maxResubscribeDelay = 30 * time.Second
+
+	// keepalive: a ping is sent every wsPingInterval, and a connection that
+	// delivers nothing (not even a pong) for wsReadTimeout is torn down, so
+	// a silently dead peer can't stall a subscription forever
+	wsPingInterval = 15 * time.Second
+	wsReadTimeout  = 2 * wsPingInterval
+	wsPingTimeout  = 5 * time.Second
+)
+
+// errConnClosed is returned for calls in flight when the connection drops.
+var errConnClosed = errors.New("websocket connection closed")
+
+// Head is a block header delivered by a newHeads subscription.
+type Head struct {
+	Number    uint64
+	Timestamp uint64
+}
+
+// isWebSocketURL reports whether endpoint uses the ws:// or wss:// scheme.
+func isWebSocketURL(endpoint string) bool {
+	lower := strings.ToLower(endpoint)
+	return strings.HasPrefix(lower, "ws://") || strings.HasPrefix(lower, "wss://")
+}
+
+// wsMessage is any message received over the WebSocket: either a response
+// to one of our requests, or a subscription notification.
+type wsMessage struct {
+	jsonRPCResponse
+	Method string `json:"method"`
+	Params struct {
+		Subscription string          `json:"subscription"`
+		Result       json.RawMessage `json:"result"`
+	} `json:"params"`
+}
+
+// wsCall is a request waiting for its response.
+type wsCall struct {
+	ch chan *jsonRPCResponse
+
+	// onNotify, if set, marks an eth_subscribe request: when it succeeds the
+	// read loop registers onNotify for the returned subscription ID before
+	// reading the next message, so no notification can overtake it
+	onNotify func(json.RawMessage)
+}
+
+// wsTransport multiplexes JSON-RPC calls and subscriptions over a single
+// WebSocket connection. the connection is dialed lazily and redialed on the
+// next call after it drops; subscriptions do not survive a drop, so
+// subscribers watch the done channel and resubscribe.
+type wsTransport struct {
+	endpoint string
+	headers  http.Header
+	logger   *slog.Logger
+
+	// mu guards the current connection and its routing tables
+	mu       sync.Mutex
+	conn     *websocket.Conn
+	done     chan struct{} // closed when conn's read loop exits
+	pending  map[uint64]*wsCall
+	subs     map[string]func(json.RawMessage)
+	lastRead time.Time // when conn last delivered a message or pong
+
+	// writeMu serializes writes, which gorilla/websocket requires
+	writeMu sync.Mutex
+}
+
+// newWSTransport creates a transport for endpoint; nothing is dialed yet.
+func newWSTransport(endpoint string, headers http.Header, logger *slog.Logger) *wsTransport {
+	return &wsTransport{
+		endpoint: endpoint,
+		headers:  headers,
+		logger:   logger,
+	}
+}
+
+// connect returns the current connection, dialing a new one if needed. ctx
+// bounds the handshake only, not the lifetime of the connection.
+func (t *wsTransport) connect(ctx context.Context) (*websocket.Conn, chan struct{}, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.conn != nil {
+		return t.conn, t.done, nil
+	}
+
+	dialer := websocket.Dialer{
+		Proxy: http.ProxyFromEnvironment,
+	}
+	conn, _, err := dialer.DialContext(ctx, t.endpoint, t.headers)
+	if err != nil {
+		return nil, nil, fmt.Errorf("websocket dial %s: %w", RedactURL(t.endpoint), err)
+	}
+
+	t.conn = conn
+	t.done = make(chan struct{})
+	t.pending = make(map[uint64]*wsCall)
+	t.subs = make(map[string]func(json.RawMessage))
+	t.lastRead = time.Now()
+
+	conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+	conn.SetPongHandler(func(string) error {
+		t.markRead(conn)
+		return nil
+	})
+	go t.readLoop(conn, t.done)
+	go t.pingLoop(conn, t.done)
+
+	t.logger.Debug("websocket connected")
+	return conn, t.done, nil
+}
+
+// readLoop routes incoming messages until the connection fails, then tears
+// it down so the next call redials.
+func (t *wsTransport) readLoop(conn *websocket.Conn, done chan struct{}) {
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.teardown(conn, done, err)
+			return
+		}
+		t.markRead(conn)
+
+		var msg wsMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.logger.Debug("ignoring malformed websocket message", "body", bodySnippet(data), "error", err)
+			continue
+		}
+
+		t.mu.Lock()
+		if msg.Method == "eth_subscription" {
+			handler := t.subs[msg.Params.Subscription]
+			t.mu.Unlock()
+			if handler != nil {
+				handler(msg.Params.Result)
+			}
+			continue
+		}
+
+		var id uint64
+		var call *wsCall
+		if err := json.Unmarshal(msg.ID, &id); err == nil {
+			call = t.pending[id]
+			delete(t.pending, id)
+		}
+		if call != nil && call.onNotify != nil && msg.Error == nil {
+			var subID string
+			if err := json.Unmarshal(msg.Result, &subID); err == nil {
+				t.subs[subID] = call.onNotify
+			}
+		}
+		t.mu.Unlock()
+
+		if call != nil {
+			resp := msg.jsonRPCResponse
+			call.ch <- &resp
+		}
+	}
+}
+
+// markRead records that conn is alive and pushes back its read deadline.
+func (t *wsTransport) markRead(conn *websocket.Conn) {
+	conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+
+	t.mu.Lock()
+	if t.conn == conn {
+		t.lastRead = time.Now()
+	}
+	t.mu.Unlock()
+}
+
+// pingLoop pings the peer until the connection is torn down. the pongs
+// keep the read deadline from expiring on an idle but healthy connection.
+func (t *wsTransport) pingLoop(conn *websocket.Conn, done chan struct{}) {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			// WriteControl may run concurrently with other writes
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsPingTimeout)); err != nil {
+				t.logger.Debug("websocket ping failed", "error", err)
+				conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// teardown forgets conn and wakes everyone waiting on it.
+func (t *wsTransport) teardown(conn *websocket.Conn, done chan struct{}, err error) {
+	t.mu.Lock()
+	if t.conn == conn {
+		t.conn = nil
+		t.pending = nil
+		t.subs = nil
+	}
+	t.mu.Unlock()
+
+	conn.Close()
+	close(done)
+	t.logger.Debug("websocket disconnected", "error", err)
+}
+
+// roundTrip sends an encoded request with the given ID and waits for its
+// response until ctx is done. for an eth_subscribe request, onNotify
+// receives the subscription's notifications; otherwise it is nil.
+// retryable reports whether the failure is transient.
+func (t *wsTransport) roundTrip(ctx context.Context, id uint64, body []byte, onNotify func(json.RawMessage)) (resp *jsonRPCResponse, retryable bool, err error) {
+	conn, done, err := t.connect(ctx)
+	if err != nil {
+		return nil, true, &TransportError{Err: err}
+	}
+
+	call := &wsCall{ch: make(chan *jsonRPCResponse, 1), onNotify: onNotify}
+	t.mu.Lock()
+	if t.conn != conn {
+		t.mu.Unlock()
+		return nil, true, &TransportError{Err: errConnClosed}
+	}
+	t.pending[id] = call
+	t.mu.Unlock()
+
+	sentAt := time.Now()
+	t.writeMu.Lock()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetWriteDeadline(deadline)
+	}
+	err = conn.WriteMessage(websocket.TextMessage, body)
+	t.writeMu.Unlock()
+	if err != nil {
+		// closing makes the read loop tear the connection down
+		conn.Close()
+		return nil, true, &TransportError{Err: fmt.Errorf("websocket write: %w", err)}
+	}
+
+	select {
+	case resp := <-call.ch:
+		return resp, false, nil
+	case <-done:
+		return nil, true, &TransportError{Err: errConnClosed}
+	case <-ctx.Done():
+		t.mu.Lock()
+		// a connection that has delivered nothing since the request went
+		// out is likely half-open; drop it rather than let every later
+		// call time out on it too
+		halfOpen := t.conn == conn && !t.lastRead.After(sentAt)
+		if t.conn == conn {
+			delete(t.pending, id)
+		}
+		t.mu.Unlock()
+		if halfOpen {
+			t.logger.Debug("closing unresponsive websocket connection")
+			conn.Close()
+		}
+		return nil, true, &TransportError{Err: fmt.Errorf("websocket request: %w", ctx.Err())}
+	}
+}
+
+// IsWebSocket reports whether the client talks to its endpoint over a
+// WebSocket (ws:// or wss://) rather than HTTP.
+func (c *Client) IsWebSocket() bool {
+	return c.ws != nil
+}
+
+// SubscribeNewHeads subscribes to new block headers (eth_subscribe
+// "newHeads") and calls onHead for each one until ctx is done. when the
+// connection drops it calls onDrop, if not nil, so the caller can discard
+// heads that are no longer being kept current, and resubscribes with
+// exponential backoff. onHead is called from the connection's read loop and
+// must not block. only ws:// and wss:// endpoints support subscriptions.
+func (c *Client) SubscribeNewHeads(ctx context.Context, onHead func(Head), onDrop func()) error {
+	if c.ws == nil {
+		return fmt.Errorf("newHeads subscription requires a ws:// or wss:// endpoint, got %s", c.redacted)
+	}
+
+	delay := minResubscribeDelay
+	for {
+		done, err := c.subscribeNewHeads(onHead)
+		if err == nil {
+			c.logger.Info("subscribed to new heads")
+			delay = minResubscribeDelay
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-done:
+			}
+			if onDrop != nil {
+				onDrop()
+			}
+			c.logger.Warn("new heads subscription dropped, resubscribing")
+		} else {
+			c.logger.Warn("new heads subscription failed", "error", err, "retry_in", delay)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxResubscribeDelay)
+	}
+}
+
+// subscribeNewHeads creates a single newHeads subscription and returns a
+// channel that is closed when the underlying connection drops.
+func (c *Client) subscribeNewHeads(onHead func(Head)) (<-chan struct{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeoutFor("eth_subscribe"))
+	defer cancel()
+
+	_, done, err := c.ws.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	id := c.lastID.Add(1)
+	body, err := json.Marshal(jsonRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "eth_subscribe",
+		Params:  []interface{}{"newHeads"},
+		ID:      id,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	handler := func(raw json.RawMessage) {
+		var header struct {
+			Number    string `json:"number"`
+			Timestamp string `json:"timestamp"`
+		}
+		if err := json.Unmarshal(raw, &header); err != nil {
+			c.logger.Debug("ignoring malformed head", "error", err)
+			return
+		}
+		number, err := parseHexUint64(header.Number)
+		if err != nil {
+			c.logger.Debug("ignoring malformed head", "error", err)
+			return
+		}
+		timestamp, err := parseHexUint64(header.Timestamp)
+		if err != nil {
+			c.logger.Debug("ignoring malformed head", "error", err)
+			return
+		}
+		onHead(Head{Number: number, Timestamp: timestamp})
+	}
+
+	// the read loop registers handler as soon as the reply arrives, before
+	// any notification that follows it
+	resp, _, err := c.ws.roundTrip(ctx, id, body, handler)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+
+	var subID string
+	if err := json.Unmarshal(resp.Result, &subID); err != nil {
+		return nil, fmt.Errorf("unmarshal subscription id: %w", err)
+	}
+	return done, nil
+}
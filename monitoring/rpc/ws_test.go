@@ -0,0 +1,144 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newWSTestClient starts a WebSocket server that hands each connection to
+// serve, and returns a client pointed at it.
+func newWSTestClient(t *testing.T, serve func(conn *websocket.Conn), opts ...Option) *Client {
+	t.Helper()
+
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		serve(conn)
+	}))
+	t.Cleanup(srv.Close)
+
+	opts = append([]Option{WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))}, opts...)
+	return NewClientWithOptions("ws"+strings.TrimPrefix(srv.URL, "http"), opts...)
+}
+
+// readRequest reads the next JSON-RPC request from conn.
+func readRequest(conn *websocket.Conn) (jsonRPCRequest, bool) {
+	var req jsonRPCRequest
+	if err := conn.ReadJSON(&req); err != nil {
+		return req, false
+	}
+	return req, true
+}
+
+func TestSubscribeNewHeads(t *testing.T) {
+	// the server sends the first head right behind the subscription reply,
+	// then drops the connection
+	c := newWSTestClient(t, func(conn *websocket.Conn) {
+		req, ok := readRequest(conn)
+		if !ok || req.Method != "eth_subscribe" {
+			return
+		}
+		conn.WriteMessage(websocket.TextMessage, []byte(result(req.ID, `"0xsub"`)))
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"0xsub","result":{"number":"0x64","timestamp":"0x5"}}}`))
+		time.Sleep(50 * time.Millisecond)
+	})
+
+	heads := make(chan Head, 1)
+	dropped := make(chan struct{}, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.SubscribeNewHeads(ctx,
+		func(h Head) {
+			select {
+			case heads <- h:
+			default:
+			}
+		},
+		func() {
+			select {
+			case dropped <- struct{}{}:
+			default:
+			}
+		},
+	)
+
+	select {
+	case h := <-heads:
+		if h != (Head{Number: 100, Timestamp: 5}) {
+			t.Errorf("head = %+v, want {Number:100 Timestamp:5}", h)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the head sent right after the subscription reply was not delivered")
+	}
+
+	select {
+	case <-dropped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("onDrop was not called when the connection dropped")
+	}
+}
+
+func TestWSTimeoutClosesSilentConnection(t *testing.T) {
+	// the server reads requests but never answers: a half-open connection
+	connections := make(chan struct{}, 2)
+	c := newWSTestClient(t, func(conn *websocket.Conn) {
+		connections <- struct{}{}
+		for {
+			if _, ok := readRequest(conn); !ok {
+				return
+			}
+		}
+	}, WithTimeout(100*time.Millisecond))
+
+	for i := 0; i < 2; i++ {
+		_, err := c.GetBlockNumber()
+		var transportErr *TransportError
+		if !errors.As(err, &transportErr) {
+			t.Fatalf("call %d: error = %v, want a *TransportError", i+1, err)
+		}
+	}
+
+	// each timed-out call should have torn its connection down, so the
+	// second call dialed a fresh one
+	if n := len(connections); n != 2 {
+		t.Errorf("%d connections dialed for 2 timed-out calls, want 2", n)
+	}
+}
+
+func TestWSResponseRouting(t *testing.T) {
+	// plain calls share the connection and get the reply carrying their ID
+	c := newWSTestClient(t, func(conn *websocket.Conn) {
+		for {
+			req, ok := readRequest(conn)
+			if !ok {
+				return
+			}
+			raw, _ := json.Marshal(req.Method)
+			conn.WriteMessage(websocket.TextMessage, []byte(result(req.ID, string(raw))))
+		}
+	})
+
+	for _, method := range []string{"eth_blockNumber", "eth_chainId"} {
+		got, err := c.call(method)
+		if err != nil {
+			t.Fatalf("call(%q) error = %v", method, err)
+		}
+		if want := `"` + method + `"`; string(got) != want {
+			t.Errorf("call(%q) = %s, want %s", method, got, want)
+		}
+	}
+}
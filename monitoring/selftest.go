@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// runSelfTest performs one full collection against the node using a
+// throwaway registry and logs a summary of every metric gathered.
+// failures are always logged; in strict mode they are also returned as an
+// error so the caller can abort startup.
+func runSelfTest(logger *slog.Logger, c prometheus.Collector, strict bool) error {
+	logger.Info("running startup self-test", "strict", strict)
+
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(c); err != nil {
+		return fmt.Errorf("self-test: register collector: %w", err)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		return fmt.Errorf("self-test: gather metrics: %w", err)
+	}
+
+	// the core metrics are emitted even when their RPC call fails, so their
+	// presence proves nothing; goat_rpc_up is 0 exactly when a core call
+	// (block number, chain ID, sync status) failed. optional metrics are
+	// reported but never fail the self-test.
+	upSeen := false
+	var failures []string
+
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			var value float64
+			switch {
+			case m.GetGauge() != nil:
+				value = m.GetGauge().GetValue()
+			case m.GetCounter() != nil:
+				value = m.GetCounter().GetValue()
+			}
+
+			labels := make([]string, 0, len(m.GetLabel()))
+			for _, lp := range m.GetLabel() {
+				labels = append(labels, fmt.Sprintf("%s=%q", lp.GetName(), lp.GetValue()))
+			}
+			sort.Strings(labels)
+
+			logger.Info("self-test metric", "metric", fmt.Sprintf("%s{%s}", mf.GetName(), strings.Join(labels, ",")), "value", value)
+
+			if mf.GetName() == "goat_rpc_up" {
+				upSeen = true
+				if value == 0 {
+					failures = append(failures, fmt.Sprintf("core RPC calls failed {%s}", strings.Join(labels, ",")))
+				}
+			}
+		}
+	}
+
+	if !upSeen {
+		failures = append(failures, "goat_rpc_up not collected")
+	}
+
+	if len(failures) == 0 {
+		logger.Info("self-test passed", "metric_families", len(families))
+		return nil
+	}
+
+	for _, f := range failures {
+		logger.Warn("self-test failure", "reason", f)
+	}
+
+	if strict {
+		return fmt.Errorf("self-test failed: %s", strings.Join(failures, "; "))
+	}
+
+	logger.Warn("self-test failed; continuing since strict mode is disabled", "failures", len(failures))
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// fakeCollector emits a fixed set of gauges, standing in for GoatCollector.
+type fakeCollector struct {
+	values map[string]float64
+}
+
+func (f fakeCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(f, ch)
+}
+
+func (f fakeCollector) Collect(ch chan<- prometheus.Metric) {
+	for name, value := range f.values {
+		desc := prometheus.NewDesc(name, name, []string{"endpoint"}, nil)
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, "http://node:8545")
+	}
+}
+
+func TestRunSelfTest(t *testing.T) {
+	healthy := fakeCollector{values: map[string]float64{
+		"goat_block_height": 100,
+		"goat_chain_id":     2345,
+		"goat_syncing":      0,
+		"goat_rpc_up":       1,
+	}}
+	unreachable := fakeCollector{values: map[string]float64{
+		"goat_block_height": 0,
+		"goat_chain_id":     0,
+		"goat_syncing":      0,
+		"goat_rpc_up":       0,
+	}}
+	noUp := fakeCollector{values: map[string]float64{
+		"goat_block_height": 100,
+	}}
+
+	tests := []struct {
+		name      string
+		collector prometheus.Collector
+		strict    bool
+		wantErr   bool
+	}{
+		{"passing, lenient", healthy, false, false},
+		{"passing, strict", healthy, true, false},
+		{"failing, lenient", unreachable, false, false},
+		{"failing, strict", unreachable, true, true},
+		{"rpc_up missing, strict", noUp, true, true},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runSelfTest(logger, tt.collector, tt.strict)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("runSelfTest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}